@@ -0,0 +1,203 @@
+// cmd/backtest/main.go
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+	"github.com/chidi150c/coinlila/internal/guards"
+	"github.com/chidi150c/coinlila/internal/risk"
+	"github.com/chidi150c/coinlila/internal/strategy"
+	"github.com/chidi150c/coinlila/internal/util"
+)
+
+// Replays a timestamp,price CSV through the strategy, the risk decisions, the
+// SafeExchange guards and the paper engine, then prints a summary.
+func main() {
+	file := flag.String("file", "", "CSV of timestamp,price rows (RFC3339 or unix seconds)")
+	symbol := flag.String("symbol", "BTC-USD", "symbol the prices belong to")
+	name := flag.String("strategy", "sma", "strategy: "+strings.Join(strategy.Names(), ", ")+" (parameters from the env, e.g. RSI_PERIOD)")
+	fast := flag.Int("fast", 10, "fast moving average length (sma, ema)")
+	slow := flag.Int("slow", 30, "slow moving average length (sma, ema)")
+	startUSD := flag.Float64("start-usd", 10000, "starting paper cash")
+	maxPos := flag.Float64("max-position-usd", 1000, "MAX_POSITION_USD")
+	maxOrder := flag.Float64("max-order-usd", 500, "MAX_ORDER_NOTIONAL_USD")
+	maxLoss := flag.Float64("max-loss-pct-day", 100, "MAX_LOSS_PCT_DAY")
+	maxLosses := flag.Int("max-consecutive-losses", 0, "MAX_CONSECUTIVE_LOSSES (losing trades in a row that halt the day, 0 = off)")
+	feeBps := flag.Float64("fee-bps", 0, "PAPER_FEE_BPS")
+	slipBps := flag.Float64("slippage-bps", 0, "PAPER_SLIPPAGE_BPS")
+	depthLevels := flag.Int("depth-levels", 0, "synthetic book levels per side; market orders fill at its VWAP (0 = mid + slippage)")
+	depthStep := flag.Float64("depth-step-bps", 5, "distance between synthetic book levels (bps of mid)")
+	depthQty := flag.Float64("depth-qty", 1, "base quantity resting at each synthetic book level")
+	sizing := flag.String("sizing", "fixed", "SIZING_MODE: fixed, kelly")
+	kellyFrac := flag.Float64("kelly-fraction", 0.5, "KELLY_FRACTION (share of the Kelly-optimal size)")
+	sellMode := flag.String("sell-mode", "full", "SELL_MODE: full, fraction, notional")
+	sellFrac := flag.Float64("sell-fraction", 0.5, "SELL_FRACTION (share of the position per sell signal)")
+	sellUSD := flag.Float64("sell-notional-usd", 0, "SELL_NOTIONAL_USD (USD sold per sell signal)")
+	tz := flag.String("tz", "UTC", "timezone for day boundaries")
+	dayOpen := flag.Duration("day-open", 0, "trading-day open after midnight in -tz (e.g. 17h; DAY_OPEN_HOUR/DAY_OPEN_MINUTE)")
+	speed := flag.Duration("speed", 0, "sleep between ticks (e.g. 50ms) for visual debugging")
+	tripsCSV := flag.String("roundtrips-csv", "", "write the FIFO-matched round-trips (entry, exit, PnL) to this CSV")
+	flag.Parse()
+	if *file == "" { log.Fatalf("backtest: -file is required") }
+
+	rows, err := readPrices(*file)
+	if err != nil { log.Fatalf("backtest: %v", err) }
+	if len(rows) == 0 { log.Fatalf("backtest: no prices in %s", *file) }
+
+	paper := exchange.NewPaper(*startUSD)
+	sim := exchange.NewPaperSim(paper, paper)
+	sim.SetCosts(*feeBps, *slipBps)
+	sim.SetSyntheticDepth(*depthLevels, *depthStep, *depthQty)
+
+	lim := risk.Limits{MaxPositionUSD: *maxPos, MaxOrderNotionalUSD: *maxOrder, MaxLossPctDay: *maxLoss,
+		MaxConsecutiveLosses: *maxLosses,
+		SizingMode: risk.SizingMode(*sizing), KellyFraction: *kellyFrac,
+		SellMode: risk.SellMode(*sellMode), SellFraction: *sellFrac, SellNotionalUSD: *sellUSD}
+	rs := risk.NewState(*startUSD, 0, util.TodayOpen(*tz, *dayOpen, rows[0].t))
+	// no rate limit or dedupe window: replayed ticks arrive far faster than live ones
+	safeEx := guards.NewSafeExchange(sim, rs, lim, 0, 0, 0, 0, 3, time.Second, 1)
+
+	strat, err := strategy.New(*name, func(k, def string) string {
+		switch k {
+		case "SMA_FAST":
+			return strconv.Itoa(*fast)
+		case "SMA_SLOW":
+			return strconv.Itoa(*slow)
+		}
+		if v := os.Getenv(k); v != "" { return v }
+		return def
+	})
+	if err != nil { log.Fatalf("backtest: %v", err) }
+
+	var buys, sells int
+	peak, maxDD, equity := *startUSD, 0.0, *startUSD
+	for _, r := range rows {
+		if !util.SameTradingDay(*tz, *dayOpen, rs.DayOpen(), r.t) {
+			rs.ResetDay(equity, util.TodayOpen(*tz, *dayOpen, r.t))
+		}
+		sim.UpdatePrice(*symbol, r.price)
+		acct, err := safeEx.Account()
+		if err != nil { log.Fatalf("backtest: account: %v", err) }
+		equity = acct.EquityUSD
+		rs.UpdateEquity(equity)
+		if equity > peak { peak = equity }
+		if dd := (peak - equity) / peak * 100; dd > maxDD { maxDD = dd }
+
+		var posQty float64
+		if pos, ok := acct.Positions[*symbol]; ok { posQty = pos.BaseQty }
+		sig := strat.Push(r.price)
+		switch {
+		case !sig.Ready:
+		case sig.Action == strategy.Buy:
+			dec := risk.DecideBuy(rs, lim, *symbol, r.price, posQty*r.price, rs.EntryPrice(*symbol))
+			if !dec.Allow { break }
+			if _, err := safeEx.PlaceMarket(*symbol, exchange.Buy, dec.Qty); err != nil {
+				log.Printf("%s BUY blocked: %v", r.t.Format(time.RFC3339), err)
+				break
+			}
+			rs.NoteFill(*symbol, true, dec.Qty, fillPrice(safeEx, *symbol, r.price))
+			buys++
+		case sig.Action == strategy.Sell:
+			dec := risk.DecideSignalSell(rs, lim, *symbol, r.price, posQty, rs.EntryPrice(*symbol))
+			if !dec.Allow { break }
+			before := sim.RealizedPnL(*symbol)
+			if _, err := safeEx.PlaceMarket(*symbol, exchange.Sell, dec.Qty); err != nil {
+				log.Printf("%s SELL blocked: %v", r.t.Format(time.RFC3339), err)
+				break
+			}
+			rs.NoteFill(*symbol, false, dec.Qty, fillPrice(safeEx, *symbol, r.price))
+			sells++
+			realized := sim.RealizedPnL(*symbol) - before
+			rs.NoteSellPnL(*symbol, realized, dec.Qty >= posQty-1e-12, lim.KellyLookback)
+		}
+		if *speed > 0 { time.Sleep(*speed) }
+	}
+
+	// win rate over round-trips: each closed lot counts once, however sells split it
+	trips := sim.ClosedTrades()
+	var wins int
+	for _, t := range trips {
+		if t.PnLUSD > 0 { wins++ }
+	}
+	winRate := math.NaN()
+	if len(trips) > 0 { winRate = float64(wins) / float64(len(trips)) * 100 }
+	if *tripsCSV != "" {
+		if err := writeRoundTrips(*tripsCSV, trips); err != nil { log.Fatalf("backtest: %v", err) }
+	}
+	fmt.Printf("ticks=%d from=%s to=%s\n", len(rows), rows[0].t.Format(time.RFC3339), rows[len(rows)-1].t.Format(time.RFC3339))
+	fmt.Printf("final_equity=%.2f (start %.2f, %+.2f%%)\n", equity, *startUSD, (equity-*startUSD) / *startUSD * 100)
+	fmt.Printf("trades=%d (buys=%d sells=%d) round_trips=%d win_rate=%.1f%% realized=%.2f\n", buys+sells, buys, sells, len(trips), winRate, sim.RealizedPnL(*symbol))
+	fmt.Printf("max_drawdown=%.2f%%\n", maxDD)
+}
+
+// fillPrice is the VWAP of the last order's walk through the book, or mid.
+func fillPrice(ex *guards.SafeExchange, symbol string, mid float64) float64 {
+	if f, ok := ex.TakeFill(symbol); ok && f.AvgPrice > 0 { return f.AvgPrice }
+	return mid
+}
+
+// writeRoundTrips writes one CSV row per matched round-trip.
+func writeRoundTrips(path string, trips []exchange.RoundTrip) error {
+	f, err := os.Create(path)
+	if err != nil { return err }
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"symbol", "qty", "entry_price", "exit_price", "pnl_usd"})
+	for _, t := range trips {
+		_ = w.Write([]string{t.Symbol,
+			strconv.FormatFloat(t.Qty, 'f', -1, 64),
+			strconv.FormatFloat(t.EntryPrice, 'f', -1, 64),
+			strconv.FormatFloat(t.ExitPrice, 'f', -1, 64),
+			strconv.FormatFloat(t.PnLUSD, 'f', -1, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil { return err }
+	return f.Close()
+}
+
+type tick struct {
+	t     time.Time
+	price float64
+}
+
+// readPrices loads timestamp,price rows; a header row or blank price is skipped.
+func readPrices(path string) ([]tick, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, err }
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	var out []tick
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF { return out, nil }
+		if err != nil { return nil, err }
+		if len(rec) < 2 { continue }
+		px, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil {
+			if line == 1 { continue } // header
+			return nil, fmt.Errorf("%s:%d: bad price %q", path, line, rec[1])
+		}
+		t, err := parseTime(strings.TrimSpace(rec[0]))
+		if err != nil { return nil, fmt.Errorf("%s:%d: %w", path, line, err) }
+		out = append(out, tick{t: t, price: px})
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil { return t, nil }
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(sec*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("bad timestamp %q", s)
+}
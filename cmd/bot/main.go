@@ -2,20 +2,28 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"math"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/chidi150c/coinlila/internal/audit"
 	"github.com/chidi150c/coinlila/internal/config"
+	"github.com/chidi150c/coinlila/internal/events"
 	"github.com/chidi150c/coinlila/internal/exchange"
 	"github.com/chidi150c/coinlila/internal/guards"
+	"github.com/chidi150c/coinlila/internal/ledger"
 	"github.com/chidi150c/coinlila/internal/metrics"
+	"github.com/chidi150c/coinlila/internal/notify"
 	"github.com/chidi150c/coinlila/internal/risk"
 	"github.com/chidi150c/coinlila/internal/strategy"
 	"github.com/chidi150c/coinlila/internal/util"
@@ -24,62 +32,265 @@ import (
 func main() {
 	// 0) env + config
 	_ = godotenv.Load(".env")
+	config.ApplySandbox() // COINBASE_SANDBOX=true with MODE=live: sandbox endpoints
+	if err := config.Validate(); err != nil {
+		log.Fatalf("config invalid:\n%v", err)
+	}
 	cfg := config.Load()
+	// LOG_FORMAT=json for aggregators (text default); std log output goes through it too
+	slog.SetDefault(util.NewLogger(getenv("LOG_FORMAT", "text"), getenv("LOG_LEVEL", "info"), os.Stderr))
 
 	// 1) metrics http server
 	metrics.Serve(cfg.HTTPListen)
-	log.Printf("coinbot starting | mode=%s symbol=%s listen=%s", cfg.Mode, cfg.Symbol, cfg.HTTPListen)
+	metrics.SetControlToken(os.Getenv("CONTROL_TOKEN"))
+	if url := os.Getenv("PUSHGATEWAY_URL"); url != "" {
+		defer metrics.StartPush(url, getenv("PUSHGATEWAY_JOB", "coinbot"), time.Duration(mustInt("PUSHGATEWAY_INTERVAL_SEC"))*time.Second)()
+	}
+	// SYMBOL may list several symbols; each is traded independently in this process
+	symbols := config.ParseSymbols(cfg.Symbol)
+	if len(symbols) == 0 { log.Fatalf("config: SYMBOL is empty") }
+	primary := symbols[0]
+	// EXCHANGE picks the live venue (and the price feed in paper mode)
+	venue := getenv("EXCHANGE", "coinbase")
+	if venue != "coinbase" && venue != "binance" { log.Fatalf("config: EXCHANGE must be coinbase or binance, got %q", venue) }
+	log.Printf("coinbot starting | mode=%s exchange=%s symbols=%s listen=%s", cfg.Mode, venue, strings.Join(symbols, ","), cfg.HTTPListen)
+	if config.Sandbox() {
+		if venue != "coinbase" { log.Fatalf("config: COINBASE_SANDBOX needs EXCHANGE=coinbase, got %q", venue) }
+		log.Printf("SANDBOX: live orders go to %s (fake money)", cfg.CBAPIBase)
+	}
+	notifier := notify.New(os.Getenv("NOTIFY_WEBHOOK_URL"))
+	fills := notify.FillReporter{
+		Live:         cfg.Mode == "live",
+		EscalateLive: getenv("NOTIFY_EVERY_LIVE_ORDER", "false") == "true",
+		N:            notifier,
+	}
+
+	// 1b) fail fast on a mistyped/delisted/restricted symbol (metadata cached for sizing)
+	products := exchange.NewProductCache(func(sym string) (exchange.ProductMeta, error) {
+		if venue == "binance" {
+			return exchange.FetchBinanceProduct(nil, getenv("BINANCE_API_BASE", "https://api.binance.us"), sym)
+		}
+		return exchange.FetchCoinbaseProduct(nil, cfg.CBAPIBase, sym)
+	})
+	if getenv("PRODUCT_CHECK", "true") == "true" {
+		for _, sym := range symbols {
+			meta, err := products.Get(sym)
+			if err == nil { err = meta.Tradable() }
+			if err != nil { log.Fatalf("product check: %v", err) }
+		}
+	}
 
 	// 2) exchange: paper first (recommended) or live coinbase
 	var ex exchange.Exchange
+	var warmup *guards.Warmup
+	var stopFeed func() // stops the WS price feeds (called on shutdown)
+	var err error
+	var paperPnL interface{ RealizedPnL(symbol string) float64 } // paper: FIFO realized PnL from the sim
+	var shadowFill *exchange.Paper // shadow: synthetic fills on the live feed
 	priceCh := make(chan exchange.Ticker, 256)
+	// explicit overflow behaviour between the WS feed and a stalled consumer
+	ticks := exchange.NewTickBuffer(256, exchange.OverflowPolicy(getenv("TICK_OVERFLOW_POLICY", string(exchange.DropOldest))))
+	go ticks.Pump(priceCh)
+	// consume runs fn over the buffered ticks; drained is closed once the last
+	// one has been handled, after shutdown closes priceCh
+	drained := make(chan struct{})
+	consume := func(fn func(t exchange.Ticker)) {
+		go func() {
+			defer close(drained)
+			for t := range ticks.C() { fn(t) }
+		}()
+	}
+	// re-subscribe the WS feed when it goes quiet for WS_STALE_SEC
+	wsStale := time.Duration(mustInt("WS_STALE_SEC")) * time.Second
+	var feed exchange.TickAger // last-tick age per symbol, to refuse trading on a stalled price
 
 	if cfg.Mode == "paper" {
 		paper := exchange.NewPaper(usdStart())
-		ex = paper
+		sim := exchange.NewPaperSim(paper, paper)
+		if getenv("PAPER_VOL_SPREAD", "false") == "true" {
+			mult, _ := strconv.ParseFloat(getenv("PAPER_SPREAD_VOL_MULT", "1"), 64)
+			sim.SetVolSpread(mult, mustF("PAPER_SPREAD_MIN_BPS"), mustF("PAPER_SPREAD_MAX_BPS"), mustInt("PAPER_SPREAD_LOOKBACK"))
+		}
+		sim.SetCosts(mustF("PAPER_FEE_BPS"), mustF("PAPER_SLIPPAGE_BPS"))
+		// synthetic order book: market orders walk it and fill at its VWAP
+		sim.SetSyntheticDepth(mustInt("PAPER_DEPTH_LEVELS"), mustF("PAPER_DEPTH_STEP_BPS"), mustF("PAPER_DEPTH_QTY"))
+		// simulated order latency: market orders fill at the price PAPER_LATENCY_MS later
+		sim.SetLatency(time.Duration(mustInt("PAPER_LATENCY_MS")) * time.Millisecond)
+		ex = sim
+		paperPnL = sim
 
-		// use coinbase WS as price feed only
-		cb := exchange.NewCoinbase(cfg.CBAPIKey, cfg.CBAPISecret, cfg.CBAPIPassphrase, cfg.CBAPIBase, cfg.CBWSURL)
-		stopWS, err := cb.StreamPrices(cfg.Symbol, priceCh)
+		// use the venue's WS as price feed only
+		var src exchange.Exchange = exchange.NewCoinbase(cfg.CBAPIKey, cfg.CBAPISecret, cfg.CBAPIPassphrase, cfg.CBAPIBase, cfg.CBWSURL)
+		if venue == "binance" { src = binanceFromEnv() }
+		rf := exchange.NewReconnectingFeed(src, wsStale)
+		rf.MaxGap = int64(mustInt("WS_MAX_GAP"))
+		feed = rf
+		stopFeed, err = streamAll(rf, symbols, priceCh)
 		if err != nil { log.Fatalf("ws connect (paper feed): %v", err) }
-		defer stopWS()
 
 		// pipe live prices into the paper engine
-		go func() {
-			for t := range priceCh {
-				paper.UpdatePrice(t.Symbol, t.Price) // ensure Paper has an exported UpdatePrice
-			}
-		}()
+		consume(func(t exchange.Ticker) { sim.UpdatePrice(t.Symbol, t.Price) })
 	} else {
-		cb := exchange.NewCoinbase(cfg.CBAPIKey, cfg.CBAPISecret, cfg.CBAPIPassphrase, cfg.CBAPIBase, cfg.CBWSURL)
-		ex = cb
-		if _, err := cb.StreamPrices(cfg.Symbol, priceCh); err != nil {
+		var live exchange.Exchange
+		if venue == "binance" {
+			live = binanceFromEnv()
+		} else {
+			live = exchange.NewCoinbaseLimits(
+				exchange.NewCoinbase(cfg.CBAPIKey, cfg.CBAPISecret, cfg.CBAPIPassphrase, cfg.CBAPIBase, cfg.CBWSURL),
+				cfg.CBAPIKey, cfg.CBAPISecret, cfg.CBAPIPassphrase, cfg.CBAPIBase)
+		}
+		ex = live
+		rf := exchange.NewReconnectingFeed(live, wsStale)
+		rf.MaxGap = int64(mustInt("WS_MAX_GAP")) // resubscribe on a sequence gap this large (0 = count only)
+		feed = rf
+		if stopFeed, err = streamAll(rf, symbols, priceCh); err != nil {
 			log.Fatalf("ws connect (live): %v", err)
 		}
+		warmDur := time.Duration(mustInt("WARMUP_SEC")) * time.Second
+		if cfg.Mode == "shadow" {
+			// live feed and account, but orders are only logged and filled on paper
+			shadowFill = exchange.NewPaper(usdStart())
+			consume(func(t exchange.Ticker) { shadowFill.UpdatePrice(t.Symbol, t.Price) })
+		} else if warmTrades := mustInt("WARMUP_TRADES"); warmDur > 0 || warmTrades > 0 {
+			// paper warm-up on the live feed; promoted to live only if it clears the bar
+			paper := exchange.NewPaper(usdStart())
+			warmup = guards.NewWarmup(paper, live, warmDur, warmTrades, time.Now())
+			warmup.MinPnLUSD = mustF("WARMUP_MIN_PNL_USD")
+			ex = warmup
+			fills.Live = false
+			consume(func(t exchange.Ticker) { paper.UpdatePrice(t.Symbol, t.Price) })
+		} else {
+			consume(func(exchange.Ticker) {}) // nothing consumes ticks in live; keep the feed flowing
+		}
 	}
 
 	// 3) account & day boundary state
-	acct, err := ex.Account()
+	acctSrc := ex
+	if shadowFill != nil { acctSrc = shadowFill } // shadow positions live on the synthetic book
+	acct, err := acctSrc.Account()
 	if err != nil { log.Fatalf("account read failed: %v", err) }
 
 	now := time.Now()
 	tz := getenv("RISK_TIMEZONE", "UTC")
-	dayMgr := risk.NewDayManager(tz, "day_snapshot.json")
-	rs := risk.NewState(acct.EquityUSD, mustInt("ERROR_COOLDOWN_SEC"), util.TodayOpen(tz, now))
+	// the trading day opens at DAY_OPEN_HOUR:DAY_OPEN_MINUTE in tz (midnight by default)
+	dayOpen := time.Duration(mustInt("DAY_OPEN_HOUR"))*time.Hour + time.Duration(mustInt("DAY_OPEN_MINUTE"))*time.Minute
+	// one bot per snapshot: a second instance would overwrite the first's day state
+	snapPath := getenv("SNAPSHOT_PATH", "day_snapshot.json")
+	releaseLock, err := util.AcquireLock(snapPath + ".lock")
+	if err != nil { log.Fatalf("snapshot lock: %v", err) }
+	defer releaseLock() // graceful shutdown returns from main
+	dayMgr := risk.NewDayManager(tz, snapPath)
+	dayMgr.Open = dayOpen
+	dayMgr.RecoveryLossPct = mustF("SNAPSHOT_RECOVERY_LOSS_PCT")
+	dayMgr.MaxStaleDays = mustInt("SNAPSHOT_MAX_AGE_DAYS")
+	dayMgr.Alert = func(msg string) { notifier.Notify(notify.Warn, msg) }
+	// per-strategy sub-budgets carved from the daily limits; their usage is kept in the day snapshot
+	buckets, err := risk.ParseBuckets(os.Getenv("RISK_BUCKETS"))
+	if err != nil { log.Fatalf("config: %v", err) }
+	dayMgr.Buckets = buckets
+	rs := risk.NewState(acct.EquityUSD, mustInt("ERROR_COOLDOWN_SEC"), util.TodayOpen(tz, dayOpen, now))
+	rs.SetErrorCooldown(time.Duration(mustInt("ERROR_COOLDOWN_SEC"))*time.Second,
+		time.Duration(mustInt("ERROR_COOLDOWN_MAX_SEC"))*time.Second) // max 0 = fixed cooldown
 	_, equityOpen := dayMgr.InitAtStartup(now, acct.EquityUSD, rs)
 	log.Printf("equity_open=%.2f", equityOpen)
+	// paper: today's realized PnL = sim total - realizedBase (keeps a restored snapshot value)
+	paperRealized := func() (sum float64) {
+		for _, sym := range symbols { sum += paperPnL.RealizedPnL(sym) }
+		return sum
+	}
+	var realizedBase float64
+	if paperPnL != nil { realizedBase = paperRealized() - rs.RealizedPnL() }
 
 	// 4) limits + safe wrapper (rate-limit, retries, dup, breaker)
 	lim := risk.Limits{
 		MaxPositionUSD:      mustF("MAX_POSITION_USD"),
 		MaxOrderNotionalUSD: mustF("MAX_ORDER_NOTIONAL_USD"),
 		MaxOrdersPerDay:     mustInt("MAX_ORDERS_PER_DAY"),
+		MinTradeUSD:         mustF("MIN_TRADE_USD"),
 		MaxLossPctDay:       mustF("MAX_LOSS_PCT_DAY"),
+		LossCapGrace:        time.Duration(mustInt("LOSS_CAP_GRACE_SEC")) * time.Second,
+		LossCapHardPct:      mustF("LOSS_CAP_HARD_PCT"),
+		MaxLossPctWeek:      mustF("MAX_LOSS_PCT_WEEK"),
+		MaxLossPctMonth:     mustF("MAX_LOSS_PCT_MONTH"),
+		MaxDrawdownPct:      mustF("MAX_DRAWDOWN_PCT"),
+		MaxConsecutiveLosses: mustInt("MAX_CONSECUTIVE_LOSSES"),
 		VolSizingOn:         getenv("VOL_SIZING_ON", "false") == "true",
 		VolLookback:         mustInt("VOL_LOOKBACK"),
+		VolWindow:           time.Duration(mustInt("VOL_LOOKBACK_SEC")) * time.Second,
+		VolMethod:           risk.VolMethod(getenv("VOL_METHOD", "simple")),
+		VolEWMALambda:       mustF("VOL_EWMA_LAMBDA"),
 		TargetRiskBp:        mustF("TARGET_RISK_BP"),
+		SizingMode:          risk.SizingMode(getenv("SIZING_MODE", "fixed")),
+		FixedBaseQty:        mustF("FIXED_BASE_QTY"),
+		FixedNotionalUSD:    mustF("FIXED_NOTIONAL_USD"),
+		KellyFraction:       mustF("KELLY_FRACTION"),
+		KellyLookback:       mustInt("KELLY_LOOKBACK"),
+		KellyMinTrades:      mustInt("KELLY_MIN_TRADES"),
+		MaxConcentrationPct: mustF("MAX_CONCENTRATION_PCT"),
+		TrailingStopPct:     mustF("TRAILING_STOP_PCT"),
+		TrailingStopATR:     mustF("TRAILING_STOP_ATR"),
+		ATRLookback:         mustInt("ATR_LOOKBACK"),
+		TakeProfitPct:       mustF("TAKE_PROFIT_PCT"),
+		MaxScaleIns:         mustInt("MAX_SCALE_INS"),
+		MaxAvgEntryDriftPct: mustF("MAX_AVG_ENTRY_DRIFT_PCT"),
+		MaxSpreadBps:        mustF("MAX_SPREAD_BPS"),
+		SellMode:            risk.SellMode(getenv("SELL_MODE", "full")),
+		SellFraction:        mustF("SELL_FRACTION"),
+		SellNotionalUSD:     mustF("SELL_NOTIONAL_USD"),
+	}
+	rs.SetVolEWMALambda(lim.VolEWMALambda) // the EWMA is updated as prices are pushed
+	lim.Instruments = map[string]risk.Instrument{}
+	for _, sym := range strings.Split(os.Getenv("INTEGER_QTY_SYMBOLS"), ",") {
+		if sym = strings.TrimSpace(sym); sym != "" {
+			lim.Instruments[sym] = risk.Instrument{IntegerQty: true}
+		}
+	}
+	// lot step, minimum size and price tick from the product metadata, so sizes aren't rejected
+	for _, sym := range symbols {
+		meta, err := products.Get(sym)
+		if err != nil {
+			log.Printf("product %s: no lot size metadata, quantities unrounded: %v", sym, err)
+			continue
+		}
+		inst := lim.Instruments[sym]
+		inst.StepSize, inst.MinQty, inst.PriceTick = meta.BaseIncrement, meta.BaseMinSize, meta.QuoteIncrement
+		lim.Instruments[sym] = inst
+	}
+	// qtyS/pxS format a symbol's quantities and prices at the venue's precision
+	qtyS := func(sym string, qty float64) string { return lim.Instruments[sym].FormatQty(qty) }
+	pxS := func(sym string, price float64) string { return lim.Instruments[sym].FormatPrice(price) }
+	if lim.TPLadder, err = risk.ParseTPLadder(os.Getenv("TP_LADDER")); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	if lim.PerSymbol, err = risk.ParseSymbolLimits(os.Getenv("PER_SYMBOL_LIMITS")); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	// per-symbol position book (avg entry, realized PnL, TP ladder progress)
+	led := ledger.New(getenv("LEDGER_PATH", "ledger.json"))
+	if cfg.Mode == "live" && warmup == nil && getenv("RECONCILE_ON_START", "true") == "true" {
+		reconcilePositions(ex, acct, led, lim, symbols, notifier)
+	}
+	for _, sym := range symbols {
+		if lp := led.Position(sym); lp.Qty > 0 {
+			rs.NoteFill(sym, true, lp.Qty, lp.AvgEntry) // resume the entry price across restarts
+		}
 	}
 
+	// per-fill trade log (JSONL, rotated at the trading-day boundary)
+	trades, err := util.OpenTradeLog(getenv("TRADE_LOG_PATH", "trades.jsonl"), tz, dayOpen, now)
+	if err != nil { log.Fatalf("trade log: %v", err) }
+	defer trades.Close()
+	if today, err := trades.Today(); err == nil && len(today) > 0 {
+		log.Printf("trade log: %d fills already recorded today", len(today))
+	}
+
+	// optional conservative equity: mark positions net of the fee to exit them
+	netExitFees := getenv("EQUITY_NET_EXIT_FEES", "false") == "true"
+	takerFeeBps := mustF("TAKER_FEE_BPS")
+
+	postTPCooldown := time.Duration(mustInt("POST_TP_COOLDOWN_SEC")) * time.Second
+
 	perMin := mustInt("RATE_LIMIT_ORDERS_PER_MIN")
 	retries := mustInt("MAX_ORDER_RETRIES")
 	backoff := time.Duration(mustInt("RETRY_BACKOFF_MS")) * time.Millisecond
@@ -92,10 +303,199 @@ func main() {
 		ex, rs, lim,
 		perMin, retries, backoff,
 		dupWin, brThresh, brCooldown, brProbes,
+		mustInt("ORDER_BURST"),
 	)
+	safeEx.SetMaxInFlight(mustInt("MAX_INFLIGHT_ORDERS"))
+	// trade events: other consumers (DB, webhooks) can Subscribe to bus as well
+	bus := events.NewBus()
+	safeEx.SetEvents(bus)
+	go func(evs <-chan events.Event) {
+		for e := range evs {
+			slog.Debug("event", "kind", string(e.Kind), "symbol", e.Symbol, "side", e.Side, "qty", e.Qty, "detail", e.Detail)
+		}
+	}(bus.Subscribe(256))
+	if shadowFill != nil { safeEx.SetShadow(shadowFill) }
+	breakerMaxOpen := time.Duration(mustInt("BREAKER_MAX_OPEN_SEC")) * time.Second
+	safeEx.SetBreakerEscalation(breakerMaxOpen, func(openFor time.Duration, level int) {
+		sev := notify.Warn
+		if level > 1 { sev = notify.Critical }
+		notifier.Notify(sev, fmt.Sprintf("circuit breaker open for %s (escalation %d)", openFor.Round(time.Second), level))
+	})
+	breakerFlatten := getenv("BREAKER_FLATTEN", "false") == "true"
+	breakerFlattened := map[string]bool{} // symbols already flattened while the breaker stays open
+	periodHalt := "" // weekly/monthly loss limit currently halting trading
+	maxStaleness := time.Duration(mustInt("MAX_PRICE_STALENESS_MS")) * time.Millisecond
+	stalePrice := map[string]bool{} // symbols currently skipped for a stale price
+	riskMetrics := metrics.NewRiskMetrics()
 
-	// 5) strategy (SMA as simple baseline)
-	sma := strategy.NewSMA(cfg.SMAFast, cfg.SMASlow)
+	// ops kill-switch: halt (and optionally flatten) while PANIC_FILE_PATH exists
+	panicSw := guards.NewPanicFile(os.Getenv("PANIC_FILE_PATH"))
+	panicFlatten := getenv("PANIC_FLATTEN", "false") == "true"
+	panicLeft := map[string]bool{} // symbols still to flatten; true once a failure was alerted
+
+	// optional advisory cross-check of the primary symbol against a second (slower) price source
+	var sanity *guards.PriceSanity
+	if url := os.Getenv("PRICE_SANITY_URL"); url != "" {
+		every := time.Duration(mustInt("PRICE_SANITY_POLL_SEC")) * time.Second
+		if every <= 0 { every = 10 * time.Second }
+		sanity = guards.NewPriceSanity(guards.NewHTTPPriceSource(url, os.Getenv("PRICE_SANITY_FIELD")),
+			primary, mustF("PRICE_DIVERGENCE_BP"), 3*every)
+		defer sanity.Start(every)()
+	}
+
+	// optional paper-only canary: periodic tiny round trip proving the pipeline fills
+	var canary *guards.Canary
+	if every := mustInt("CANARY_INTERVAL_SEC"); every > 0 {
+		qty, _ := strconv.ParseFloat(getenv("CANARY_QTY", "0.0001"), 64)
+		if canary, err = guards.NewCanary(cfg.Mode, safeEx, primary, qty, time.Duration(every)*time.Second); err != nil {
+			log.Printf("canary disabled: %v", err)
+		}
+	}
+
+	noFill := guards.NewFillWatchdog(time.Duration(mustInt("MAX_NO_FILL_SEC"))*time.Second, nil)
+
+	// bookSell applies a sell to the ledger and books its realized PnL
+	// the venue's confirmed fill (live), when there is one, replaces the requested
+	// qty and the mid price so the ledger and trade log see what really happened;
+	// its venue ID lets the ledger ignore a fill it has already applied
+	confirmedFill := func(sym string, qty, px float64) (string, float64, float64) {
+		if f, ok := safeEx.TakeFill(sym); ok && f.FilledQty > 0 && f.AvgPrice > 0 {
+			return f.ID, f.FilledQty, f.AvgPrice
+		}
+		return "", qty, px
+	}
+	// sellFill and buyFill book an executed fill as it is (id "" = unknown)
+	sellFill := func(id, sym string, qty, px float64) float64 {
+		if id != "" && led.Seen(id) { return 0 } // already booked: don't count it twice
+		realized := led.ApplyFill(ledger.Fill{ID: id, Symbol: sym, Side: exchange.Sell, Qty: qty, Price: px})
+		if paperPnL == nil { rs.AddRealizedPnL(realized) } // paper books it from the sim each tick
+		rs.NoteFill(sym, false, qty, px)
+		if warmup != nil { warmup.NoteTrade(realized) }
+		rs.NoteSellPnL(sym, realized, led.Position(sym).Qty <= 0, lim.KellyLookback) // one outcome per round trip
+		recordTrade(trades, sym, exchange.Sell, qty, px, realized)
+		return realized
+	}
+	buyFill := func(id, sym string, qty, px float64) {
+		if id != "" && led.Seen(id) { return }
+		led.ApplyFill(ledger.Fill{ID: id, Symbol: sym, Side: exchange.Buy, Qty: qty, Price: px})
+		rs.NoteFill(sym, true, qty, px)
+		recordTrade(trades, sym, exchange.Buy, qty, px, 0)
+	}
+	bookSell := func(sym string, qty, px float64) float64 {
+		id, qty, px := confirmedFill(sym, qty, px)
+		return sellFill(id, sym, qty, px)
+	}
+	bookBuy := func(sym string, qty, px float64) {
+		id, qty, px := confirmedFill(sym, qty, px)
+		buyFill(id, sym, qty, px)
+	}
+
+	// decision trail: one intent event per allowed/denied decision (AUDIT_LOG_PATH= turns it off)
+	var auditLog *audit.Log
+	auditPath, set := os.LookupEnv("AUDIT_LOG_PATH")
+	if !set { auditPath = "audit.jsonl" }
+	if auditPath != "" {
+		if auditLog, err = audit.Open(auditPath); err != nil { log.Fatalf("audit log: %v", err) }
+		defer auditLog.Close()
+	}
+	notifyDenials := getenv("NOTIFY_DENIALS", "false") == "true"
+	emitIntent := func(sym, signal string, side exchange.Side, price float64, dec risk.Decision) {
+		_ = auditLog.Write(audit.Intent{
+			Time: time.Now(), Symbol: sym, Side: string(side), Signal: signal, Price: price,
+			Qty: dec.Qty, NotionalUSD: dec.NotionalUSD, Allowed: dec.Allow, Code: string(dec.Code), Reason: dec.Reason,
+		})
+		if dec.Allow { return }
+		metrics.ObserveDenial(string(dec.Code))
+		bus.Publish(events.Event{Kind: events.OrderDenied, Symbol: sym, Side: string(side), Qty: dec.Qty, Detail: string(dec.Code) + ": " + dec.Reason})
+		slog.Info("order denied", "symbol", sym, "signal", signal, "side", string(side), "price", price,
+			"qty", dec.Qty, "notional", dec.NotionalUSD, "code", string(dec.Code), "reason", dec.Reason)
+		if notifyDenials {
+			notifier.Notify(notify.Info, fmt.Sprintf("%s %s %s denied (%s): %s", sym, signal, side, dec.Code, dec.Reason))
+		}
+	}
+	// orderBlocked logs an allowed order the safety layer (or venue) refused
+	orderBlocked := func(sym, signal string, side exchange.Side, qty float64, err error) {
+		slog.Warn("order blocked", "symbol", sym, "signal", signal, "side", string(side), "qty", qty, "err", err)
+	}
+
+	// exitPosition sells qty through the risk checks for a protective exit; signal
+	// names it in the audit trail. Reports whether the order went out.
+	exitPosition := func(sym, signal string, qty, price float64, detail string) (risk.Decision, bool) {
+		label := strings.ToUpper(strings.ReplaceAll(signal, "_", " "))
+		dec := risk.RoundQty(risk.DecideSell(rs, lim, sym, price, qty, rs.EntryPrice(sym)), lim, sym, price)
+		emitIntent(sym, signal, exchange.Sell, price, dec)
+		if !dec.Allow {
+			return dec, false
+		}
+		if _, err := safeEx.PlaceMarket(sym, exchange.Sell, dec.Qty); err != nil {
+			orderBlocked(sym, signal, exchange.Sell, dec.Qty, err)
+			return dec, false
+		}
+		bookSell(sym, dec.Qty, price)
+		noFill.NoteFill()
+		fills.Report(fmt.Sprintf("%s %s SELL %s @ %s | %s", sym, label, qtyS(sym, dec.Qty), pxS(sym, price), detail),
+			fillAttrs(sym, signal, exchange.Sell, dec.Qty, price)...)
+		return dec, true
+	}
+
+	// 5) strategy, selected by name from the strategy registry (STRATEGY=sma|ema|rsi|
+	// macd|bollinger), one independent instance per symbol; SYMBOL_STRATEGIES=
+	// ETH-USD:rsi,... runs another one on some symbols. A symbol's strategy ID keys
+	// its budget bucket (MA_TYPE=ema only swaps the MA, not the ID).
+	strategyOf, err := strategy.ParseAssignments(os.Getenv("SYMBOL_STRATEGIES"))
+	if err != nil { log.Fatalf("config: %v", err) }
+	for _, sym := range symbols {
+		if strategyOf[sym] == "" { strategyOf[sym] = getenv("STRATEGY", "sma") }
+	}
+	strategyName := func(sym string) string {
+		if name := strategyOf[sym]; name != "sma" || getenv("MA_TYPE", "sma") != "ema" { return name }
+		return "ema"
+	}
+	strategyParams := func(k, def string) string {
+		switch k {
+		case "SMA_FAST":
+			return strconv.Itoa(cfg.SMAFast)
+		case "SMA_SLOW":
+			return strconv.Itoa(cfg.SMASlow)
+		}
+		return getenv(k, def)
+	}
+	// CONFIRM_BAR_TICKS=N: buys also need the same strategy, run on N-tick bars, to agree
+	confirmTicks := mustInt("CONFIRM_BAR_TICKS")
+	strategies := map[string]strategy.Strategy{}
+	for _, sym := range symbols {
+		if strategies[sym], err = strategy.New(strategyName(sym), strategyParams); err != nil {
+			log.Fatalf("config: STRATEGY: %v", err)
+		}
+		if confirmTicks > 1 {
+			higher, _ := strategy.New(strategyName(sym), strategyParams)
+			strategies[sym] = strategy.NewConfirmed(strategies[sym], higher, confirmTicks)
+		}
+	}
+
+	// EXECUTION_MODE=target: rebalance toward a scaled target exposure instead of
+	// all-in/all-out on crossovers
+	targetMode := getenv("EXECUTION_MODE", "cross") == "target"
+	for _, sym := range symbols {
+		if name := strategyName(sym); targetMode && name != "sma" && name != "ema" {
+			log.Fatalf("config: EXECUTION_MODE=target needs a moving-average strategy, not %s on %s", name, sym)
+		}
+	}
+	targetFullPct, _ := strconv.ParseFloat(getenv("TARGET_FULL_SPREAD_PCT", "1"), 64)
+	rebalanceBand := mustF("REBALANCE_BAND_USD")
+
+	// optional: evaluate/execute once per closed bar instead of every tick (matches backtests)
+	var bars map[string]*strategy.BarAggregator
+	if getenv("BAR_CLOSE_ONLY", "false") == "true" {
+		bars = map[string]*strategy.BarAggregator{}
+		for _, sym := range symbols {
+			if n := mustInt("BAR_TICKS"); n > 0 {
+				bars[sym] = strategy.NewCountBarAggregator(n) // BAR_TICKS: bars of n ticks instead of BAR_SEC
+			} else {
+				bars[sym] = strategy.NewBarAggregator(time.Duration(mustInt("BAR_SEC")) * time.Second)
+			}
+		}
+	}
 
 	// 6) loop + shutdown
 	tick := time.NewTicker(2 * time.Second)
@@ -107,65 +507,364 @@ func main() {
 		select {
 		case <-quit:
 			log.Println("shutting down")
+			// flush state, cancel resting orders and stop the feed, bounded by 5s
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				dayMgr.PersistProgress(time.Now(), rs)
+				for _, sym := range symbols {
+					if err := safeEx.CancelAll(sym); err != nil {
+						log.Printf("shutdown: cancel open orders on %s: %v", sym, err)
+					}
+				}
+				// stop every stream before closing priceCh (no send can race the
+				// close), then let the consumer finish the ticks already buffered
+				if stopFeed != nil { stopFeed() }
+				close(priceCh)
+				<-drained
+				if path := os.Getenv("TRADE_LOG_CSV"); path != "" {
+					if err := trades.ExportCSV(path); err != nil { log.Printf("trade log export: %v", err) }
+				}
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				log.Printf("shutdown: timed out, exiting anyway")
+			}
+			cancel()
 			return
 
 		case <-tick.C:
 			now = time.Now()
 
-			// price (from exchange BBA; WS feeds exchange impl)
-			bid, ask, err := safeEx.BestBidAsk(cfg.Symbol)
-			if err != nil || bid <= 0 || ask <= 0 {
+			// prices (from exchange BBA; WS feeds exchange impl); symbols without one wait
+			prices, spreads := map[string]float64{}, map[string]float64{}
+			for _, sym := range symbols {
+				bid, ask, err := safeEx.BestBidAsk(sym)
+				if err == nil && bid > 0 && ask > 0 {
+					prices[sym] = (bid + ask) / 2
+					spreads[sym] = risk.SpreadBps(bid, ask)
+					metrics.SetSpreadBps(sym, spreads[sym])
+				}
+			}
+			if len(prices) == 0 {
 				continue // wait until we have a price
 			}
-			price := (bid + ask) / 2
 
-			// risk: vol window + equity
-			if lim.VolLookback > 0 { rs.PushPrice(price, lim.VolLookback) }
+			if done, promoted, reason := warmupEvaluate(warmup, now); promoted {
+				// fresh baseline: paper positions/equity don't exist on the live account
+				led.Reset()
+				rs.ResetEntries() // paper entries would drive TP and scale-in checks on live positions
+				if a, err := safeEx.Account(); err == nil {
+					acct = a
+					rs.ResetDay(a.EquityUSD, rs.DayOpen())
+				}
+				fills.Live = true
+				notifier.Notify(notify.Warn, "paper warm-up passed, execution switched to LIVE")
+			} else if done {
+				notifier.Notify(notify.Critical, "refusing to go live: "+reason)
+			}
+
+			// risk: per-symbol vol windows + account equity
+			if lim.VolLookback > 0 || lim.VolWindow > 0 {
+				for sym, price := range prices { rs.PushSymbolPriceAt(sym, now, price, lim.VolLookback, lim.VolWindow) }
+			}
 			if acct, err = safeEx.Account(); err == nil {
-				rs.UpdateEquity(acct.EquityUSD)
+				equity := acct.EquityUSD
+				if netExitFees {
+					var posUSD float64
+					for sym, price := range prices {
+						usd, _ := currentExposureForSymbol(acct, sym, price)
+						posUSD += usd
+					}
+					equity = risk.NetOfExitFees(equity, posUSD, takerFeeBps)
+				}
+				rs.UpdateEquity(equity)
+				metrics.SetReady(true) // a valid price and an account read: feed is live
 			}
 
+			// resting orders the venue filled on its own since the last tick
+			for _, f := range safeEx.TakeRestingFills() {
+				if f.Side == exchange.Sell {
+					sellFill(f.ID, f.Symbol, f.Qty, f.Price)
+				} else {
+					buyFill(f.ID, f.Symbol, f.Qty, f.Price)
+				}
+				noFill.NoteFill()
+				fills.Report(fmt.Sprintf("%s RESTING %s %s @ %s (%s)", f.Symbol, f.Side, qtyS(f.Symbol, f.Qty), pxS(f.Symbol, f.Price), f.ID),
+					fillAttrs(f.Symbol, "resting", f.Side, f.Qty, f.Price)...)
+			}
+			if paperPnL != nil { rs.SetRealizedPnL(paperRealized() - realizedBase) }
+
 			// day boundary (persist & reset when needed)
-			if dayMgr.RolloverIfNeeded(now, rs.EquityNowUSD, rs) {
-				// optional: send alert here
+			if wasHalted := rs.Halted(); dayMgr.RolloverIfNeeded(now, rs.EquityNow(), rs) {
+				if wasHalted { bus.Publish(events.Event{Kind: events.Resume, Detail: "day rollover"}) }
+				if paperPnL != nil { realizedBase = paperRealized() }
+			}
+			// daily loss kill-switch and losing-streak halt: latched (and persisted) until the next rollover
+			if wasHalted := rs.Halted(); rs.CheckHalt(now, lim) && !wasHalted {
+				if rs.HaltCause() == risk.DenyLossStreak {
+					slog.Error("trading halted", "limit", "loss_streak", "streak", rs.LossStreak(), "max", lim.MaxConsecutiveLosses)
+					notifier.Notify(notify.Critical, fmt.Sprintf("%d consecutive losing trades, trading halted until day rollover", rs.LossStreak()))
+					bus.Publish(events.Event{Kind: events.Halt, Detail: "loss streak"})
+				} else {
+					slog.Error("trading halted", "limit", "daily", "equity", rs.EquityNow(), "equity_open", rs.EquityAtOpen())
+					notifier.Notify(notify.Critical, "daily loss limit hit, trading halted until day rollover")
+					bus.Publish(events.Event{Kind: events.Halt, Detail: "daily loss limit"})
+				}
 			}
 			dayMgr.PersistProgress(now, rs)
+			metrics.SetOrdersRemainingToday(rs.RemainingOrdersToday(lim))
+			metrics.SetDrawdownPct(rs.DrawdownPct())
+			metrics.SetHalted(rs.Halted())
+			metrics.SetLossStreak(rs.LossStreak())
+			metrics.SetErrorCooldown(rs.EffectiveCooldown())
+			riskMetrics.SetEquity(rs.EquityNow(), rs.EquityAtOpen())
+			riskMetrics.SetOrdersToday(rs.OrdersToday())
+			metrics.SetStatus(metrics.Status{
+				Mode: cfg.Mode, Symbols: symbols,
+				EquityUSD: rs.EquityNow(), EquityAtOpenUSD: rs.EquityAtOpen(), DayPnLPct: metrics.DayPnLPct(rs.EquityNow(), rs.EquityAtOpen()),
+				OrdersToday: rs.OrdersToday(), OrdersRemaining: rs.RemainingOrdersToday(lim), BreakerState: safeEx.BreakerState(), Halted: rs.Halted(),
+				BreakerRetryIn: safeEx.TimeUntilHalfOpen().Seconds(), OrdersInWindow: safeEx.OrdersInWindow(),
+				OpenOrders: len(safeEx.OpenOrders()),
+				Paused: metrics.Paused(), LastTick: now,
+			})
 
-			// strategy signal
-			have, fast, slow, cross := sma.Push(price)
-			if !have { continue }
+			if halted, changed := panicSw.Check(); halted {
+				if changed {
+					notifier.Notify(notify.Critical, "panic file present, trading halted: "+panicSw.Path)
+					bus.Publish(events.Event{Kind: events.Halt, Detail: "panic file"})
+					if panicFlatten {
+						for _, sym := range symbols { panicLeft[sym] = false }
+					}
+				}
+				// symbols without a price yet, or whose sell failed, are retried on later ticks
+				for sym, alerted := range panicLeft {
+					price, ok := prices[sym]
+					if !ok { continue }
+					_, posQty := currentExposureForSymbol(acct, sym, price)
+					if posQty <= 0 {
+						delete(panicLeft, sym)
+						continue
+					}
+					if _, err := safeEx.PlaceMarket(sym, exchange.Sell, posQty); err != nil {
+						if !alerted { notifier.Notify(notify.Critical, "panic flatten failed, retrying: "+sym+": "+err.Error()) }
+						panicLeft[sym] = true
+						continue
+					}
+					delete(panicLeft, sym)
+					bookSell(sym, posQty, price)
+					fills.Report(fmt.Sprintf("PANIC flatten %s SELL %s @ %s", sym, qtyS(sym, posQty), pxS(sym, price)),
+						fillAttrs(sym, "panic_flatten", exchange.Sell, posQty, price)...)
+				}
+				noFill.Pause()
+				continue
+			} else if changed {
+				clear(panicLeft)
+				notifier.Notify(notify.Info, "panic file removed, trading resumed")
+				bus.Publish(events.Event{Kind: events.Resume, Detail: "panic file"})
+			}
+			if metrics.Paused() || rs.Halted() { // POST /pause: equity and snapshots keep updating, no orders
+				noFill.Pause()
+				continue
+			}
 
-			// current exposure (best-effort from Account())
-			posUSD, posQty := currentExposureForSymbol(acct, cfg.Symbol, price)
+			// weekly/monthly drawdown: halt until the breached period rolls over
+			if breach := rs.PeriodLossBreach(lim); breach != "" {
+				if breach != periodHalt {
+					_, weekEq := rs.WeekOpen()
+					_, monthEq := rs.MonthOpen()
+					slog.Error("trading halted", "limit", breach, "equity", rs.EquityNow(),
+						"equity_week_open", weekEq, "equity_month_open", monthEq)
+					notifier.Notify(notify.Critical, breach+" loss limit breached, trading halted")
+					bus.Publish(events.Event{Kind: events.Halt, Detail: breach + " loss limit"})
+					periodHalt = breach
+				}
+				noFill.Pause()
+				continue
+			} else if periodHalt != "" {
+				notifier.Notify(notify.Info, periodHalt+" loss limit period rolled over, trading resumed")
+				bus.Publish(events.Event{Kind: events.Resume, Detail: periodHalt + " loss limit"})
+				periodHalt = ""
+			}
+			if noFill.Check() {
+				notifier.Notify(notify.Warn, "no fills for longer than MAX_NO_FILL_SEC while trading is active")
+			}
 
-			switch cross {
-			case "golden": // try to buy
-				dec := risk.DecideBuy(rs, lim, price, posUSD)
-				if dec.Allow {
-					if _, err := safeEx.PlaceMarket(cfg.Symbol, exchange.Buy, dec.Qty); err != nil {
-						log.Printf("BUY blocked: %v", err)
-					} else {
-						log.Printf("BUY %.8f @ %.2f | fast=%.2f slow=%.2f | notional=%.2f",
-							dec.Qty, price, fast, slow, dec.NotionalUSD)
+			// breaker stuck open: escalate; optionally flatten once the venue answers again
+			if openFor := safeEx.CheckBreakerOpen(now); openFor == 0 {
+				clear(breakerFlattened)
+			} else if breakerFlatten && breakerMaxOpen > 0 && openFor >= breakerMaxOpen {
+				for sym, price := range prices {
+					if breakerFlattened[sym] { continue }
+					if _, posQty := currentExposureForSymbol(acct, sym, price); posQty > 0 {
+						if _, err := safeEx.PlaceMarketBypass(sym, exchange.Sell, posQty); err == nil {
+							breakerFlattened[sym] = true
+							bookSell(sym, posQty, price)
+							fills.Report(fmt.Sprintf("BREAKER flatten %s SELL %s @ %s", sym, qtyS(sym, posQty), pxS(sym, price)),
+								fillAttrs(sym, "breaker_flatten", exchange.Sell, posQty, price)...)
+							notifier.Notify(notify.Critical, sym+" position flattened while circuit breaker stayed open")
+						}
 					}
-				} else {
-					log.Printf("BUY denied: %s", dec.Reason)
 				}
+			}
+
+			if err := canary.MaybeRun(now); err != nil {
+				notifier.Notify(notify.Critical, err.Error())
+			}
 
-			case "death": // try to sell (size-limited)
-				dec := risk.DecideSell(rs, lim, price, posQty)
-				if dec.Allow {
-					if _, err := safeEx.PlaceMarket(cfg.Symbol, exchange.Sell, dec.Qty); err != nil {
-						log.Printf("SELL blocked: %v", err)
+			// each symbol is evaluated independently; `continue` moves on to the next one
+			for _, sym := range symbols {
+				price, ok := prices[sym]
+				if !ok { continue }
+
+				// no trading on a price the feed has stopped updating
+				age := feed.LastTickAge(sym)
+				metrics.SetPriceStaleness(sym, age)
+				if maxStaleness > 0 && age > maxStaleness {
+					if !stalePrice[sym] {
+						slog.Warn("price stale, not trading", "symbol", sym, "age", age.String(), "max", maxStaleness.String())
+						stalePrice[sym] = true
+					}
+					continue
+				} else if stalePrice[sym] {
+					slog.Info("price fresh again, trading resumed", "symbol", sym)
+					stalePrice[sym] = false
+				}
+
+				if sanity != nil && sym == primary {
+					if ok, divBp := sanity.Check(price); !ok {
+						log.Printf("price sanity: venue %.2f diverges %.1fbp from reference, not trading %s", price, divBp, sym)
+						continue
+					}
+				}
+
+				// protective exits: the whole position goes, whatever the strategy says
+				var sold float64 // qty the exits below sold this tick; acct predates them
+				if _, posQty := currentExposureForSymbol(acct, sym, price); posQty <= 0 {
+					rs.ResetPeak(sym)
+				} else if entry := rs.EntryPrice(sym); rs.TakeProfitTriggered(lim, entry, price) {
+					if dec, ok := exitPosition(sym, "take_profit_target", posQty, price, "entry="+pxS(sym, entry)); ok { sold += dec.Qty }
+				} else if peak := rs.Peak(sym); rs.TrailingStopTriggered(lim, sym, price) {
+					if dec, ok := exitPosition(sym, "trailing_stop", posQty, price, "peak="+pxS(sym, peak)); ok {
+						sold += dec.Qty
+						if dec.Qty >= posQty { rs.ResetPeak(sym) }
+					}
+				}
+
+				// take-profit ladder: reduce-only partial closes as price reaches each level
+				if lp := led.Position(sym); len(lim.TPLadder) > 0 {
+					if qty, ok := risk.NextTPTranche(lim.TPLadder, lp.TPFilled, lp.TPPartial, lp.AvgEntry, price, lp.PeakQty, lp.Qty); ok {
+						dec := risk.RoundQty(risk.DecideSell(rs, lim, sym, price, qty, lp.AvgEntry), lim, sym, price)
+						emitIntent(sym, "take_profit", exchange.Sell, price, dec)
+						if !dec.Allow {
+							// logged by emitIntent
+						} else if _, err := safeEx.PlaceMarket(sym, exchange.Sell, dec.Qty); err != nil {
+							orderBlocked(sym, "take_profit", exchange.Sell, dec.Qty, err)
+						} else {
+							bookSell(sym, dec.Qty, price)
+							sold += dec.Qty
+							// the level is done only once its whole tranche is sold; a capped
+							// order leaves the rest pending (a remainder under one lot can't be sold)
+							inst := lim.Instruments[sym]
+							if left := qty - dec.Qty; left <= 1e-12 || left < math.Max(inst.StepSize, inst.MinQty) {
+								led.MarkTPFilled(sym, now)
+							} else {
+								led.NoteTPPartial(sym, dec.Qty, now)
+							}
+							noFill.NoteFill()
+							fills.Report(fmt.Sprintf("%s TP level %d: SELL %s @ %s | entry=%s", sym, lp.TPFilled+1, qtyS(sym, dec.Qty), pxS(sym, price), pxS(sym, lp.AvgEntry)),
+								fillAttrs(sym, "take_profit", exchange.Sell, dec.Qty, price)...)
+						}
+					}
+				}
+
+				// strategy signal
+				sigPrice := price
+				if bars != nil {
+					bar, closed := bars[sym].Push(now, price)
+					if !closed { continue } // intra-bar: no evaluation, no orders
+					sigPrice = bar.Close
+				}
+				sig := strategies[sym].Push(sigPrice)
+				if !sig.Ready { continue }
+				fast, slow, action := sig.Fast, sig.Slow, sig.Action
+
+				// current exposure (best-effort from Account()), less what the exits just sold
+				posUSD, posQty := currentExposureForSymbol(acct, sym, price)
+				if sold > 0 {
+					posQty = math.Max(0, posQty-sold)
+					posUSD = posQty * price
+				}
+
+				if targetMode {
+					target := strategy.TargetExposure(fast, slow, targetFullPct)
+					buy, dec, ok := risk.Rebalance(rs, lim, sym, price, posUSD, posQty, rs.EntryPrice(sym), target, rebalanceBand)
+					if !ok { continue }
+					side := exchange.Sell
+					if buy { side = exchange.Buy }
+					dec = risk.CapBySpread(dec, lim, spreads[sym])
+					dec = risk.RoundQty(buckets.Check(dec, strategyOf[sym], price), lim, sym, price)
+					emitIntent(sym, "rebalance", side, price, dec)
+					if !dec.Allow {
+						// logged by emitIntent
+					} else if _, err := safeEx.PlaceMarket(sym, side, dec.Qty); err != nil {
+						orderBlocked(sym, "rebalance", side, dec.Qty, err)
 					} else {
-						log.Printf("SELL %.8f @ %.2f | fast=%.2f slow=%.2f | notional=%.2f",
-							dec.Qty, price, fast, slow, dec.NotionalUSD)
+						if side == exchange.Sell {
+							bookSell(sym, dec.Qty, price)
+						} else {
+							bookBuy(sym, dec.Qty, price)
+						}
+						buckets.Note(strategyOf[sym], dec.NotionalUSD)
+						noFill.NoteFill()
+						fills.Report(fmt.Sprintf("%s REBALANCE %s %s @ %s | target=%.2f notional=%.2f", sym, side, qtyS(sym, dec.Qty), pxS(sym, price), target, dec.NotionalUSD),
+							fillAttrs(sym, "rebalance", side, dec.Qty, price)...)
 					}
-				} else {
-					log.Printf("SELL denied: %s", dec.Reason)
+					continue
+				}
+
+				switch action {
+				case strategy.Buy: // try to buy
+					dec := risk.DecideBuy(rs, lim, sym, price, posUSD, rs.EntryPrice(sym))
+					marked, _ := led.MarkedValues(prices)
+					dec = risk.CapByConcentration(dec, lim, marked[sym], acct.EquityUSD)
+					dec = risk.CapByExitCooldown(dec, led.Position(sym).LastTPExitAt, postTPCooldown, now)
+					dec = risk.CapBySpread(dec, lim, spreads[sym])
+					dec = risk.RoundQty(buckets.Check(dec, strategyOf[sym], price), lim, sym, price)
+					emitIntent(sym, action, exchange.Buy, price, dec)
+					if dec.Allow {
+						if _, err := safeEx.PlaceMarket(sym, exchange.Buy, dec.Qty); err != nil {
+							orderBlocked(sym, action, exchange.Buy, dec.Qty, err)
+						} else {
+							bookBuy(sym, dec.Qty, price)
+							buckets.Note(strategyOf[sym], dec.NotionalUSD)
+							noFill.NoteFill()
+							fills.Report(fmt.Sprintf("%s BUY %s @ %s | fast=%s slow=%s | notional=%.2f",
+								sym, qtyS(sym, dec.Qty), pxS(sym, price), pxS(sym, fast), pxS(sym, slow), dec.NotionalUSD), fillAttrs(sym, action, exchange.Buy, dec.Qty, price)...)
+						}
+					}
+
+				case strategy.Sell: // try to sell (size-limited)
+					dec := risk.DecideSignalSell(rs, lim, sym, price, posQty, rs.EntryPrice(sym))
+					dec = risk.CapBySpread(dec, lim, spreads[sym])
+					dec = risk.RoundQty(buckets.Check(dec, strategyOf[sym], price), lim, sym, price)
+					emitIntent(sym, action, exchange.Sell, price, dec)
+					if dec.Allow {
+						if _, err := safeEx.PlaceMarket(sym, exchange.Sell, dec.Qty); err != nil {
+							orderBlocked(sym, action, exchange.Sell, dec.Qty, err)
+						} else {
+							bookSell(sym, dec.Qty, price)
+							buckets.Note(strategyOf[sym], dec.NotionalUSD)
+							noFill.NoteFill()
+							fills.Report(fmt.Sprintf("%s SELL %s @ %s | fast=%s slow=%s | notional=%.2f",
+								sym, qtyS(sym, dec.Qty), pxS(sym, price), pxS(sym, fast), pxS(sym, slow), dec.NotionalUSD), fillAttrs(sym, action, exchange.Sell, dec.Qty, price)...)
+						}
+					}
+				default:
+					// flat
 				}
-			default:
-				// flat
 			}
 		}
 	}
@@ -173,7 +872,34 @@ func main() {
 
 // ----- helpers -----
 
-func usdStart() float64 { return 1000.0 }
+func usdStart() float64 { return config.PaperStartUSD() }
+
+// fillAttrs are the structured fields logged with every fill.
+func fillAttrs(symbol, signal string, side exchange.Side, qty, price float64) []any {
+	return []any{"symbol", symbol, "signal", signal, "side", string(side), "qty", qty, "price", price, "notional", qty * price}
+}
+
+// streamAll subscribes every symbol onto out; the returned func stops them all.
+func streamAll(ex exchange.Exchange, symbols []string, out chan<- exchange.Ticker) (func(), error) {
+	var stops []func()
+	stopAll := func() {
+		for _, stop := range stops { stop() }
+	}
+	for _, sym := range symbols {
+		stop, err := ex.StreamPrices(sym, out)
+		if err != nil {
+			stopAll()
+			return nil, fmt.Errorf("%s: %w", sym, err)
+		}
+		stops = append(stops, stop)
+	}
+	return stopAll, nil
+}
+
+func recordTrade(l *util.TradeLog, symbol string, side exchange.Side, qty, price, realized float64) {
+	err := l.Record(util.Trade{Symbol: symbol, Side: string(side), Qty: qty, Price: price, RealizedPnLUSD: realized})
+	if err != nil { log.Printf("trade log: %v", err) }
+}
 
 func mustInt(k string) int {
 	v, _ := strconv.Atoi(os.Getenv(k))
@@ -183,11 +909,50 @@ func mustF(k string) float64 {
 	v, _ := strconv.ParseFloat(os.Getenv(k), 64)
 	return v
 }
+// reconcilePositions makes the ledger agree with what the venue holds, so a
+// crash between an order and its ledger write doesn't leave the bot unaware of
+// a position (and buying it again). A holding the ledger doesn't explain is
+// priced from the venue's recent fills when it lists them, else at the mid.
+func reconcilePositions(ex exchange.Exchange, acct exchange.Account, led *ledger.Ledger, lim risk.Limits, symbols []string, n notify.Notifier) {
+	for _, sym := range symbols {
+		venueQty := acct.Positions[sym].BaseQty
+		var entry float64
+		if fl, ok := ex.(exchange.FillLister); ok {
+			if fills, err := fl.RecentFills(sym); err == nil {
+				entry = ledger.EntryFromFills(fills)
+			} else {
+				log.Printf("reconcile %s: recent fills: %v", sym, err)
+			}
+		}
+		if entry <= 0 {
+			if bid, ask, err := ex.BestBidAsk(sym); err == nil && bid > 0 && ask > 0 { entry = (bid + ask) / 2 }
+		}
+		tol := lim.Instruments[sym].StepSize / 2
+		if tol <= 0 { tol = 1e-8 }
+		before, changed := led.Reconcile(sym, venueQty, entry, tol)
+		if !changed { continue }
+		inst := lim.Instruments[sym]
+		n.Notify(notify.Warn, fmt.Sprintf("reconcile %s: ledger held %s @ %s, venue holds %s; ledger corrected (entry %s)",
+			sym, inst.FormatQty(before.Qty), inst.FormatPrice(before.AvgEntry), inst.FormatQty(venueQty), inst.FormatPrice(led.Position(sym).AvgEntry)))
+	}
+}
+
+// binanceFromEnv builds the Binance.US backend from BINANCE_* settings.
+func binanceFromEnv() *exchange.Binance {
+	return exchange.NewBinance(os.Getenv("BINANCE_API_KEY"), os.Getenv("BINANCE_API_SECRET"),
+		getenv("BINANCE_API_BASE", "https://api.binance.us"), getenv("BINANCE_WS_URL", "wss://stream.binance.us:9443"))
+}
+
 func getenv(k, def string) string {
 	if v := os.Getenv(k); v != "" { return v }
 	return def
 }
 
+func warmupEvaluate(w *guards.Warmup, now time.Time) (done, promoted bool, reason string) {
+	if w == nil { return false, false, "" }
+	return w.Evaluate(now)
+}
+
 func currentExposureForSymbol(ac exchange.Account, symbol string, price float64) (posUSD, posQty float64) {
 	if ac.Positions == nil { return 0, 0 }
 	if pos, ok := ac.Positions[symbol]; ok {
@@ -0,0 +1,53 @@
+// Package audit appends the bot's decision trail to a JSONL file.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Intent is emitted for every trade decision, allowed or denied, with the
+// context that produced it.
+type Intent struct {
+	Time        time.Time `json:"time"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Signal      string    `json:"signal"` // what asked for the trade (golden, death, take_profit, ...)
+	Price       float64   `json:"price"`
+	Qty         float64   `json:"qty"`
+	NotionalUSD float64   `json:"notional_usd"`
+	Allowed     bool      `json:"allowed"`
+	Code        string    `json:"code,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Log is an append-only JSONL writer. A nil *Log discards writes.
+type Log struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens (or creates) path for appending.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil { return nil, err }
+	return &Log{f: f}, nil
+}
+
+// Write appends v as one JSON line.
+func (l *Log) Write(v any) error {
+	if l == nil { return nil }
+	b, err := json.Marshal(v)
+	if err != nil { return err }
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.f.Write(append(b, '\n'))
+	return err
+}
+
+func (l *Log) Close() error {
+	if l == nil { return nil }
+	return l.f.Close()
+}
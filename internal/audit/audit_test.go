@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/risk"
+)
+
+func TestAllowedAndDeniedDecisionsAreLogged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := risk.NewState(10000, 0, time.Now())
+	rs.UpdateEquity(10000)
+	lim := risk.Limits{MaxPositionUSD: 1000, MaxOrderNotionalUSD: 100}
+	for _, price := range []float64{100, 0} { // no price: denied
+		dec := risk.DecideBuy(rs, lim, "BTC-USD", price, 0, 0)
+		err := l.Write(Intent{Time: time.Now(), Symbol: "BTC-USD", Side: "BUY", Signal: "golden", Price: price,
+			Qty: dec.Qty, NotionalUSD: dec.NotionalUSD, Allowed: dec.Allow, Code: string(dec.Code), Reason: dec.Reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Intent
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var in Intent
+		if err := json.Unmarshal(sc.Bytes(), &in); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, in)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d intents logged, want 2", len(got))
+	}
+	if !got[0].Allowed || got[0].NotionalUSD != 100 || got[0].Code != "" {
+		t.Errorf("allowed intent = %+v", got[0])
+	}
+	if got[1].Allowed || got[1].Code != string(risk.DenyNoPrice) || got[1].Reason == "" {
+		t.Errorf("denied intent = %+v, want code %s with a reason", got[1], risk.DenyNoPrice)
+	}
+}
+
+func TestNilLogDiscards(t *testing.T) {
+	var l *Log
+	if err := l.Write(Intent{Symbol: "BTC-USD"}); err != nil {
+		t.Errorf("nil log Write: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("nil log Close: %v", err)
+	}
+}
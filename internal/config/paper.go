@@ -0,0 +1,20 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// DefaultPaperStartUSD is the paper account's starting equity when
+// PAPER_START_USD is unset or invalid.
+const DefaultPaperStartUSD = 1000.0
+
+// PaperStartUSD reads PAPER_START_USD, falling back to DefaultPaperStartUSD when
+// it is unset, unparseable or not positive so existing setups keep working.
+func PaperStartUSD() float64 {
+	v, err := strconv.ParseFloat(os.Getenv("PAPER_START_USD"), 64)
+	if err != nil || v <= 0 {
+		return DefaultPaperStartUSD
+	}
+	return v
+}
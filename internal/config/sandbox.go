@@ -0,0 +1,31 @@
+package config
+
+import "os"
+
+// Coinbase Exchange sandbox endpoints: real order flow, fake money.
+const (
+	SandboxAPIBase = "https://api-public.sandbox.exchange.coinbase.com"
+	SandboxWSURL   = "wss://ws-feed-public.sandbox.exchange.coinbase.com"
+)
+
+// Sandbox reports whether COINBASE_SANDBOX=true is in effect, which it only is
+// with MODE=live: paper and shadow never send orders, so there is nothing to
+// point at the sandbox.
+func Sandbox() bool {
+	return os.Getenv("COINBASE_SANDBOX") == "true" && os.Getenv("MODE") == "live"
+}
+
+// ApplySandbox points COINBASE_API_BASE and COINBASE_WS_URL at the sandbox when
+// Sandbox() is on, so everything reading them (Load, preflight) follows. The
+// sandbox URLs can be overridden with COINBASE_SANDBOX_API_BASE and
+// COINBASE_SANDBOX_WS_URL; the production ones in .env are deliberately not
+// honoured, or a leftover production URL would send sandbox keys to it. Call it
+// before Validate and Load.
+func ApplySandbox() {
+	if !Sandbox() { return }
+	base, ws := os.Getenv("COINBASE_SANDBOX_API_BASE"), os.Getenv("COINBASE_SANDBOX_WS_URL")
+	if base == "" { base = SandboxAPIBase }
+	if ws == "" { ws = SandboxWSURL }
+	os.Setenv("COINBASE_API_BASE", base)
+	os.Setenv("COINBASE_WS_URL", ws)
+}
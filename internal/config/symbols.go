@@ -0,0 +1,17 @@
+package config
+
+import "strings"
+
+// ParseSymbols splits a SYMBOL value such as "BTC-USD,ETH-USD" into its symbols,
+// trimming blanks and dropping duplicates; a single symbol yields one entry.
+func ParseSymbols(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, sym := range strings.Split(s, ",") {
+		if sym = strings.TrimSpace(sym); sym != "" && !seen[sym] {
+			seen[sym] = true
+			out = append(out, sym)
+		}
+	}
+	return out
+}
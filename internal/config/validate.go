@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KnobError is one invalid or missing setting found by Validate.
+type KnobError struct {
+	Key     string
+	Value   string
+	Problem string
+}
+
+func (e *KnobError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %s", e.Key, e.Problem)
+	}
+	return fmt.Sprintf("%s=%q: %s", e.Key, e.Value, e.Problem)
+}
+
+// PositiveKnobs must be set to a number > 0: a zero here silently disables a
+// safety limit (e.g. MAX_LOSS_PCT_DAY=0 turns the kill-switch off).
+var PositiveKnobs = []string{
+	"MAX_POSITION_USD",
+	"MAX_LOSS_PCT_DAY",
+}
+
+// RequiredKnobs must be set explicitly, even to 0, so the choice is deliberate.
+var RequiredKnobs = []string{
+	"MIN_TRADE_USD",
+}
+
+// NumericKnobs may be left unset (zero = off/default) but, when set, must be a
+// number >= 0; a typo would otherwise read as zero.
+var NumericKnobs = []string{
+	"MAX_ORDER_NOTIONAL_USD", "MAX_ORDERS_PER_DAY", "MIN_TRADE_USD",
+	"MAX_LOSS_PCT_WEEK", "MAX_LOSS_PCT_MONTH", "MAX_DRAWDOWN_PCT", "MAX_CONSECUTIVE_LOSSES", "LOSS_CAP_GRACE_SEC", "LOSS_CAP_HARD_PCT",
+	"VOL_LOOKBACK", "VOL_LOOKBACK_SEC", "VOL_EWMA_LAMBDA", "TARGET_RISK_BP",
+	"FIXED_BASE_QTY", "FIXED_NOTIONAL_USD", "KELLY_FRACTION", "KELLY_LOOKBACK", "KELLY_MIN_TRADES",
+	"TRAILING_STOP_PCT", "TRAILING_STOP_ATR", "ATR_LOOKBACK", "TAKE_PROFIT_PCT",
+	"SELL_FRACTION", "SELL_NOTIONAL_USD", "MAX_CONCENTRATION_PCT", "MAX_SCALE_INS", "MAX_AVG_ENTRY_DRIFT_PCT", "MAX_SPREAD_BPS",
+	"ERROR_COOLDOWN_SEC", "ERROR_COOLDOWN_MAX_SEC", "MAX_PRICE_STALENESS_MS", "WS_STALE_SEC", "WS_MAX_GAP",
+	"RATE_LIMIT_ORDERS_PER_MIN", "ORDER_BURST", "DUP_SUPPRESS_WINDOW_MS", "MAX_ORDER_RETRIES", "RETRY_BACKOFF_MS",
+	"BREAKER_THRESHOLD", "BREAKER_COOLDOWN_SEC", "BREAKER_HALFOPEN_PROBES", "BREAKER_MAX_OPEN_SEC", "MAX_INFLIGHT_ORDERS",
+	"PAPER_FEE_BPS", "PAPER_SLIPPAGE_BPS", "PAPER_DEPTH_LEVELS", "PAPER_DEPTH_STEP_BPS", "PAPER_DEPTH_QTY", "PAPER_LATENCY_MS",
+	"CONFIRM_BAR_TICKS", "BAR_TICKS", "TAKER_FEE_BPS", "WARMUP_SEC", "WARMUP_TRADES", "SNAPSHOT_RECOVERY_LOSS_PCT", "SNAPSHOT_MAX_AGE_DAYS",
+}
+
+// Validate checks the environment the bot runs from and returns every problem
+// at once (each a *KnobError, joined with errors.Join), or nil. It is the one
+// list of checks shared by the bot's startup and the preflight script.
+func Validate() error {
+	var errs []error
+	bad := func(key, problem string) {
+		errs = append(errs, &KnobError{Key: key, Value: os.Getenv(key), Problem: problem})
+	}
+
+	mode := os.Getenv("MODE")
+	switch mode {
+	case "paper", "shadow", "live":
+	case "":
+		bad("MODE", "missing (paper, shadow or live)")
+	default:
+		bad("MODE", "must be paper, shadow or live")
+	}
+
+	symbols := ParseSymbols(os.Getenv("SYMBOL"))
+	if len(symbols) == 0 { bad("SYMBOL", "missing") }
+	for _, sym := range symbols {
+		if base, quote, ok := strings.Cut(sym, "-"); !ok || base == "" || quote == "" {
+			bad("SYMBOL", sym+" is not BASE-QUOTE (e.g. BTC-USD)")
+		}
+	}
+
+	switch venue := os.Getenv("EXCHANGE"); venue {
+	case "", "coinbase":
+		for _, k := range []string{"COINBASE_API_BASE", "COINBASE_WS_URL"} {
+			if os.Getenv(k) == "" { bad(k, "missing") }
+		}
+		if mode == "live" || mode == "shadow" {
+			for _, k := range []string{"COINBASE_API_KEY", "COINBASE_API_SECRET"} {
+				if os.Getenv(k) == "" { bad(k, "missing (needed for "+mode+" mode)") }
+			}
+		}
+	case "binance":
+		if mode == "live" || mode == "shadow" {
+			for _, k := range []string{"BINANCE_API_KEY", "BINANCE_API_SECRET"} {
+				if os.Getenv(k) == "" { bad(k, "missing (needed for "+mode+" mode)") }
+			}
+		}
+	default:
+		bad("EXCHANGE", "must be coinbase or binance")
+	}
+
+	for _, r := range []struct {
+		key string
+		max int
+	}{{"DAY_OPEN_HOUR", 23}, {"DAY_OPEN_MINUTE", 59}} {
+		if v := os.Getenv(r.key); v != "" {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 || n > r.max { bad(r.key, fmt.Sprintf("must be a whole number 0-%d", r.max)) }
+		}
+	}
+	switch os.Getenv("SELL_MODE") {
+	case "", "full":
+	case "fraction":
+		if f, err := strconv.ParseFloat(os.Getenv("SELL_FRACTION"), 64); err != nil || f <= 0 || f > 1 {
+			bad("SELL_FRACTION", "must be in (0, 1] with SELL_MODE=fraction")
+		}
+	case "notional":
+		if f, err := strconv.ParseFloat(os.Getenv("SELL_NOTIONAL_USD"), 64); err != nil || f <= 0 {
+			bad("SELL_NOTIONAL_USD", "must be > 0 with SELL_MODE=notional")
+		}
+	default:
+		bad("SELL_MODE", "must be full, fraction or notional")
+	}
+	switch os.Getenv("VOL_METHOD") {
+	case "", "simple", "ewma":
+	default:
+		bad("VOL_METHOD", "must be simple or ewma")
+	}
+	if v := os.Getenv("VOL_EWMA_LAMBDA"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 1 { bad("VOL_EWMA_LAMBDA", "must be below 1") }
+	}
+
+	if v := os.Getenv("PAPER_START_USD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 { bad("PAPER_START_USD", "must be a positive number") }
+	}
+	for _, k := range PositiveKnobs {
+		v := os.Getenv(k)
+		if v == "" {
+			bad(k, "missing (must be > 0)")
+		} else if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 {
+			bad(k, "must be a number > 0")
+		}
+	}
+	for _, k := range RequiredKnobs {
+		if os.Getenv(k) == "" { bad(k, "missing (set 0 to turn it off)") }
+	}
+	for _, k := range NumericKnobs {
+		if v := os.Getenv(k); v != "" {
+			if f, err := strconv.ParseFloat(v, 64); err != nil || f < 0 { bad(k, "must be a number >= 0") }
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+// Package events publishes trading events (orders, denials, breaker and halt
+// changes) to any number of subscribers, so consumers such as databases or
+// webhooks can be added without touching the trading loop.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricDropped = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_events_dropped_total", Help: "Events dropped because a subscriber's buffer was full"})
+
+func init() { prometheus.MustRegister(metricDropped) }
+
+// Kind names what happened.
+type Kind string
+
+const (
+	OrderPlaced Kind = "order_placed" // the venue accepted an order
+	OrderFailed Kind = "order_failed" // the safety layer or the venue refused an allowed order
+	OrderDenied Kind = "order_denied" // risk checks denied the order before it was sent
+	Breaker     Kind = "breaker"      // circuit breaker changed state (Detail is the new state)
+	Halt        Kind = "halt"         // trading halted (Detail says why)
+	Resume      Kind = "resume"       // a halt was lifted
+)
+
+// Event is one trading event. Symbol, Side and Qty are empty for events that
+// are not about an order.
+type Event struct {
+	Time   time.Time
+	Kind   Kind
+	Symbol string
+	Side   string
+	Qty    float64
+	Detail string // denial reason, error, breaker state, halt cause
+}
+
+// Bus fans events out to subscribers. Publishing never blocks: an event a
+// subscriber has no room for is dropped and counted. A nil *Bus discards
+// everything, so publishers need not check.
+type Bus struct {
+	mu   sync.Mutex
+	subs []chan Event
+}
+
+func NewBus() *Bus { return &Bus{} }
+
+// Subscribe returns a channel receiving every event published from now on,
+// buffered to hold buffer events (minimum 1).
+func (b *Bus) Subscribe(buffer int) <-chan Event {
+	if buffer < 1 { buffer = 1 }
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	b.subs = append(b.subs, ch)
+	b.mu.Unlock()
+	return ch
+}
+
+// Publish delivers e to every subscriber that has room; Time defaults to now.
+func (b *Bus) Publish(e Event) {
+	if b == nil { return }
+	if e.Time.IsZero() { e.Time = time.Now() }
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			metricDropped.Inc()
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Binance is a Binance.US spot backend: signed REST for the account and market
+// orders, the public WS ticker stream for prices. Symbols keep the bot's
+// "BTC-USD" form and are mapped to Binance's "BTCUSD" on the wire.
+type Binance struct {
+	key, secret, apiBase, wsURL string
+	client                      *http.Client
+
+	mu        sync.Mutex
+	confirmed map[string]OrderStatus // market fills by client order ID
+}
+
+func NewBinance(key, secret, apiBase, wsURL string) *Binance {
+	return &Binance{
+		key: key, secret: secret,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		wsURL:   strings.TrimRight(wsURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func binanceSymbol(symbol string) string { return strings.ReplaceAll(symbol, "-", "") }
+
+// BestBidAsk reads the top of book (GET /api/v3/ticker/bookTicker).
+func (b *Binance) BestBidAsk(symbol string) (float64, float64, error) {
+	var out struct {
+		Bid string `json:"bidPrice"`
+		Ask string `json:"askPrice"`
+	}
+	if err := b.do(http.MethodGet, "/api/v3/ticker/bookTicker", url.Values{"symbol": {binanceSymbol(symbol)}}, false, &out); err != nil {
+		return 0, 0, err
+	}
+	bid, _ := strconv.ParseFloat(out.Bid, 64)
+	ask, _ := strconv.ParseFloat(out.Ask, 64)
+	return bid, ask, nil
+}
+
+// Account maps the spot balances into the Account shape: USD counts at face
+// value, every other asset with a USD market is a position under "<ASSET>-USD" and counts
+// toward equity at its last price. Assets without a USD market are ignored.
+func (b *Binance) Account() (Account, error) {
+	var acct struct {
+		Balances []struct {
+			Asset  string `json:"asset"`
+			Free   string `json:"free"`
+			Locked string `json:"locked"`
+		} `json:"balances"`
+	}
+	if err := b.do(http.MethodGet, "/api/v3/account", url.Values{}, true, &acct); err != nil {
+		return Account{}, err
+	}
+	var prices []struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := b.do(http.MethodGet, "/api/v3/ticker/price", nil, false, &prices); err != nil {
+		return Account{}, err
+	}
+	last := make(map[string]float64, len(prices))
+	for _, p := range prices {
+		last[p.Symbol], _ = strconv.ParseFloat(p.Price, 64)
+	}
+
+	out := Account{Positions: map[string]Position{}}
+	for _, bal := range acct.Balances {
+		free, _ := strconv.ParseFloat(bal.Free, 64)
+		locked, _ := strconv.ParseFloat(bal.Locked, 64)
+		qty := free + locked
+		if qty <= 0 { continue }
+		if bal.Asset == "USD" {
+			out.EquityUSD += qty
+			continue
+		}
+		px, ok := last[bal.Asset+"USD"]
+		if !ok { continue }
+		out.EquityUSD += qty * px
+		sym := bal.Asset + "-USD"
+		pos := out.Positions[sym]
+		pos.BaseQty = qty
+		out.Positions[sym] = pos
+	}
+	return out, nil
+}
+
+// FetchBinanceProduct reads a symbol's trading rules (GET
+// {apiBase}/api/v3/exchangeInfo?symbol=): the LOT_SIZE step and minimum and the
+// PRICE_FILTER tick, in the same shape as the Coinbase metadata. client may be nil.
+func FetchBinanceProduct(client *http.Client, apiBase, symbol string) (ProductMeta, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(strings.TrimRight(apiBase, "/") + "/api/v3/exchangeInfo?symbol=" + url.QueryEscape(binanceSymbol(symbol)))
+	if err != nil {
+		return ProductMeta{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return ProductMeta{}, fmt.Errorf("%s: unknown product on this venue", symbol) // -1121 invalid symbol
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ProductMeta{}, fmt.Errorf("%s: exchange info http %d", symbol, resp.StatusCode)
+	}
+	var raw struct {
+		Symbols []struct {
+			Status      string   `json:"status"`
+			SpotAllowed bool     `json:"isSpotTradingAllowed"`
+			OrderTypes  []string `json:"orderTypes"`
+			Filters     []struct {
+				Type     string `json:"filterType"`
+				StepSize string `json:"stepSize"`
+				MinQty   string `json:"minQty"`
+				TickSize string `json:"tickSize"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ProductMeta{}, fmt.Errorf("%s: decode exchange info: %w", symbol, err)
+	}
+	if len(raw.Symbols) == 0 {
+		return ProductMeta{}, fmt.Errorf("%s: unknown product on this venue", symbol)
+	}
+	s := raw.Symbols[0]
+	num := func(s string) float64 { v, _ := strconv.ParseFloat(s, 64); return v }
+	meta := ProductMeta{Symbol: symbol, Status: "online", TradingDisabled: !s.SpotAllowed, LimitOnly: true}
+	if s.Status != "TRADING" { meta.Status = strings.ToLower(s.Status) }
+	for _, t := range s.OrderTypes {
+		if t == "MARKET" { meta.LimitOnly = false }
+	}
+	for _, f := range s.Filters {
+		switch f.Type {
+		case "LOT_SIZE":
+			meta.BaseIncrement, meta.BaseMinSize = num(f.StepSize), num(f.MinQty)
+		case "PRICE_FILTER":
+			meta.QuoteIncrement = num(f.TickSize)
+		}
+	}
+	return meta, nil
+}
+
+// StreamPrices subscribes to <symbol>@ticker and forwards each last-trade price.
+// The returned stop closes the connection and waits for the reader to exit.
+func (b *Binance) StreamPrices(symbol string, out chan<- Ticker) (func(), error) {
+	conn, _, err := websocket.DefaultDialer.Dial(b.wsURL+"/ws/"+strings.ToLower(binanceSymbol(symbol))+"@ticker", nil)
+	if err != nil {
+		return nil, Transient(fmt.Errorf("binance ws dial: %w", err))
+	}
+	done, quit := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg struct {
+				Last string `json:"c"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return // closed by stop, or dropped: ReconnectingFeed re-subscribes
+			}
+			if px, err := strconv.ParseFloat(msg.Last, 64); err == nil && px > 0 {
+				select {
+				case out <- Ticker{Symbol: symbol, Price: px}:
+				case <-quit:
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit); conn.Close() })
+		<-done
+	}, nil
+}
+
+// PlaceMarket places a market order without a client order ID.
+func (b *Binance) PlaceMarket(symbol string, side Side, qty float64) (Order, error) {
+	return b.PlaceMarketID(symbol, side, qty, "")
+}
+
+// PlaceMarketID posts a market order (POST /api/v3/order) with
+// newClientOrderId set, which Binance rejects if repeated. Binance answers a
+// market order with its fills, so the executed quantity and average price are
+// kept for Confirmed without polling.
+func (b *Binance) PlaceMarketID(symbol string, side Side, qty float64, clientOrderID string) (Order, error) {
+	if qty <= 0 {
+		return Order{}, Permanent(fmt.Errorf("market order needs qty > 0 (qty=%v)", qty))
+	}
+	q := url.Values{
+		"symbol":           {binanceSymbol(symbol)},
+		"side":             {strings.ToUpper(string(side))},
+		"type":             {"MARKET"},
+		"quantity":         {strconv.FormatFloat(qty, 'f', -1, 64)},
+		"newOrderRespType": {"FULL"},
+	}
+	if clientOrderID != "" { q.Set("newClientOrderId", clientOrderID) }
+	var out struct {
+		OrderID     int64  `json:"orderId"`
+		Status      string `json:"status"`
+		ExecutedQty string `json:"executedQty"`
+		QuoteQty    string `json:"cummulativeQuoteQty"`
+	}
+	if err := b.do(http.MethodPost, "/api/v3/order", q, true, &out); err != nil {
+		return Order{}, err
+	}
+	st := OrderStatus{
+		ID: strconv.FormatInt(out.OrderID, 10), Symbol: symbol, Side: side,
+		Status: out.Status, Done: out.Status == "FILLED" || out.Status == "EXPIRED",
+	}
+	st.FilledQty, _ = strconv.ParseFloat(out.ExecutedQty, 64)
+	if quote, _ := strconv.ParseFloat(out.QuoteQty, 64); st.FilledQty > 0 {
+		st.AvgPrice = quote / st.FilledQty
+	}
+	if st.FilledQty <= 0 {
+		return Order{}, Permanent(fmt.Errorf("market order %s done without a fill (status %q)", st.ID, st.Status))
+	}
+	if clientOrderID != "" {
+		b.mu.Lock()
+		if b.confirmed == nil { b.confirmed = map[string]OrderStatus{} }
+		b.confirmed[clientOrderID] = st
+		b.mu.Unlock()
+	}
+	return Order{ID: st.ID, Symbol: symbol, Side: side, Qty: st.FilledQty, Price: st.AvgPrice}, nil
+}
+
+// Confirmed returns (and forgets) the fill of the market order placed with
+// clientOrderID.
+func (b *Binance) Confirmed(clientOrderID string) (OrderStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.confirmed[clientOrderID]
+	delete(b.confirmed, clientOrderID)
+	return st, ok
+}
+
+// do sends a REST request and decodes the JSON reply into out. Signed requests
+// carry a timestamp and an HMAC-SHA256 of the query string, hex encoded.
+func (b *Binance) do(method, path string, q url.Values, signed bool, out any) error {
+	if q == nil { q = url.Values{} }
+	if signed {
+		q.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		q.Set("recvWindow", "5000")
+	}
+	qs := q.Encode()
+	if signed {
+		mac := hmac.New(sha256.New, []byte(b.secret))
+		mac.Write([]byte(qs))
+		qs += "&signature=" + hex.EncodeToString(mac.Sum(nil)) // must come last
+	}
+	u := b.apiBase + path
+	if qs != "" { u += "?" + qs }
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return err
+	}
+	if signed { req.Header.Set("X-MBX-APIKEY", b.key) }
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return Transient(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return classifyHTTP(resp.StatusCode, fmt.Errorf("binance %s %s http %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(raw))))
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("binance %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBinancePlaceMarketSignsAndParsesFull(t *testing.T) {
+	const key, secret = "api-key", "api-secret"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/order" {
+			t.Errorf("request %s %s, want POST /api/v3/order", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("X-MBX-APIKEY"); got != key {
+			t.Errorf("X-MBX-APIKEY = %q", got)
+		}
+		// the signature is the hex HMAC-SHA256 of everything before it
+		raw := r.URL.RawQuery
+		i := strings.LastIndex(raw, "&signature=")
+		if i < 0 {
+			t.Fatalf("query %q is not signed last", raw)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(raw[:i]))
+		if sig := raw[i+len("&signature="):]; sig != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("signature %s does not match the query", sig)
+		}
+		q := r.URL.Query()
+		for k, want := range map[string]string{"symbol": "BTCUSD", "side": "BUY", "type": "MARKET",
+			"quantity": "0.015", "newOrderRespType": "FULL", "newClientOrderId": "cid-1", "recvWindow": "5000"} {
+			if got := q.Get(k); got != want {
+				t.Errorf("%s = %q, want %q", k, got, want)
+			}
+		}
+		if q.Get("timestamp") == "" {
+			t.Errorf("no timestamp")
+		}
+		w.Write([]byte(`{"symbol":"BTCUSD","orderId":28457,"clientOrderId":"cid-1","transactTime":1507725176595,
+			"price":"0.00000000","origQty":"0.01500000","executedQty":"0.01500000","cummulativeQuoteQty":"1500.75000000",
+			"status":"FILLED","timeInForce":"GTC","type":"MARKET","side":"BUY",
+			"fills":[{"price":"100000.00","qty":"0.01000000","commission":"1.0","commissionAsset":"USD"},
+				{"price":"100150.00","qty":"0.00500000","commission":"0.5","commissionAsset":"USD"}]}`))
+	}))
+	defer srv.Close()
+
+	b := NewBinance(key, secret, srv.URL, "")
+	ord, err := b.PlaceMarketID("BTC-USD", Buy, 0.015, "cid-1")
+	if err != nil {
+		t.Fatalf("PlaceMarketID: %v", err)
+	}
+	if ord.ID != "28457" || ord.Symbol != "BTC-USD" || ord.Side != Buy || ord.Qty != 0.015 || ord.Price != 100050 {
+		t.Errorf("order = %+v, want 28457 BTC-USD BUY 0.015 @ 100050", ord)
+	}
+	st, ok := b.Confirmed("cid-1")
+	if !ok || st.ID != "28457" || !st.Done || st.FilledQty != 0.015 || st.AvgPrice != 100050 {
+		t.Errorf("confirmed = %+v, %v", st, ok)
+	}
+	if _, ok := b.Confirmed("cid-1"); ok {
+		t.Errorf("confirmed fill returned twice")
+	}
+}
+
+func TestBinanceRejectionIsPermanent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-2010,"msg":"Account has insufficient balance for requested action."}`))
+	}))
+	defer srv.Close()
+	_, err := NewBinance("k", "s", srv.URL, "").PlaceMarketID("BTC-USD", Sell, 1, "cid-2")
+	if err == nil || IsTransient(err) || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Errorf("err = %v, want a permanent rejection", err)
+	}
+}
@@ -0,0 +1,109 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ClientIDPlacer is implemented by venues that accept a client order ID with a
+// market order and treat a repeated ID as the same order, so a retry after a
+// timeout cannot double-place. Kept separate from Exchange like LimitPlacer.
+type ClientIDPlacer interface {
+	PlaceMarketID(symbol string, side Side, qty float64, clientOrderID string) (Order, error)
+}
+
+// PlaceMarketID places a market order tagged with clientOrderID. A repeated ID
+// places nothing and returns the order originally placed under it. A fill that
+// walked the order book is kept for Confirmed with its VWAP.
+func (p *PaperSim) PlaceMarketID(symbol string, side Side, qty float64, clientOrderID string) (Order, error) {
+	if clientOrderID == "" {
+		return p.PlaceMarket(symbol, side, qty)
+	}
+	p.delay()
+	p.idMu.Lock()
+	defer p.idMu.Unlock()
+	if ord, ok := p.byClientID[clientOrderID]; ok {
+		return ord, nil
+	}
+	ord, vwap, err := p.placeMarket(symbol, side, qty)
+	if err != nil {
+		return ord, err
+	}
+	if vwap > 0 {
+		p.mu.Lock()
+		if p.confirmed == nil { p.confirmed = map[string]OrderStatus{} }
+		p.confirmed[clientOrderID] = OrderStatus{ID: clientOrderID, Symbol: symbol, Side: side, FilledQty: qty, AvgPrice: vwap, Status: "done", Done: true}
+		p.mu.Unlock()
+	}
+	if p.byClientID == nil { p.byClientID = map[string]Order{} }
+	p.byClientID[clientOrderID] = ord
+	// a retry follows within seconds, so only the latest IDs need remembering
+	if p.clientIDs = append(p.clientIDs, clientOrderID); len(p.clientIDs) > maxClientIDs {
+		delete(p.byClientID, p.clientIDs[0])
+		p.clientIDs = p.clientIDs[1:]
+	}
+	return ord, nil
+}
+
+// PlaceMarketID posts a market order with client_oid set (POST /orders), which
+// the venue uses to recognise a resubmitted order. The venue's order is only
+// identified by the client ID here; the returned Order carries no fill details.
+// Once accepted, the order is polled until done (see SetFillTimeout) and the
+// actual fill is kept for Confirmed.
+func (c *CoinbaseLimits) PlaceMarketID(symbol string, side Side, qty float64, clientOrderID string) (Order, error) {
+	if clientOrderID == "" {
+		return c.Exchange.PlaceMarket(symbol, side, qty)
+	}
+	if qty <= 0 {
+		return Order{}, Permanent(fmt.Errorf("market order needs qty > 0 (qty=%v)", qty))
+	}
+	body, _ := json.Marshal(map[string]string{
+		"type":       "market",
+		"side":       strings.ToLower(string(side)),
+		"product_id": symbol,
+		"size":       strconv.FormatFloat(qty, 'f', -1, 64),
+		"client_oid": clientOrderID,
+	})
+	req, err := http.NewRequest(http.MethodPost, c.apiBase+"/orders", bytes.NewReader(body))
+	if err != nil {
+		return Order{}, err
+	}
+	if err := c.sign(req, "/orders", body); err != nil {
+		return Order{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Order{}, Transient(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return Order{}, classifyHTTP(resp.StatusCode, fmt.Errorf("market order http %d: %s", resp.StatusCode, strings.TrimSpace(string(raw))))
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil || out.ID == "" || c.fillTimeout <= 0 {
+		return Order{}, nil // accepted; fill confirmation unavailable
+	}
+	// a market order can fill in pieces: wait for the venue's final quantity/price
+	st, err := c.awaitFill(out.ID)
+	if err != nil {
+		slog.Warn("market order fill not confirmed", "symbol", symbol, "id", out.ID, "err", err)
+		return Order{}, nil // it was accepted; don't let a retry place it again
+	}
+	if st.FilledQty <= 0 {
+		return Order{}, Permanent(fmt.Errorf("market order %s done without a fill (status %q)", out.ID, st.Status))
+	}
+	c.mu.Lock()
+	if c.confirmed == nil { c.confirmed = map[string]OrderStatus{} }
+	c.confirmed[clientOrderID] = st
+	c.mu.Unlock()
+	return Order{}, nil
+}
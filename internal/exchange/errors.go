@@ -0,0 +1,45 @@
+package exchange
+
+import "errors"
+
+// TransientError marks a failure that may succeed on retry: network errors,
+// HTTP 5xx and 429.
+type TransientError struct{ Err error }
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// PermanentError marks a failure that will fail the same way on retry, e.g. a
+// rejected order (insufficient funds, bad size): HTTP 4xx other than 429.
+type PermanentError struct{ Err error }
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Transient wraps err as a TransientError (nil stays nil).
+func Transient(err error) error {
+	if err == nil { return nil }
+	return &TransientError{Err: err}
+}
+
+// Permanent wraps err as a PermanentError (nil stays nil).
+func Permanent(err error) error {
+	if err == nil { return nil }
+	return &PermanentError{Err: err}
+}
+
+// IsTransient reports whether err is worth retrying. Only errors marked
+// permanent are not; unclassified errors are retried as before.
+func IsTransient(err error) bool {
+	var p *PermanentError
+	return err != nil && !errors.As(err, &p)
+}
+
+// classifyHTTP marks an HTTP error response: 429 and 5xx are transient, any
+// other status permanent.
+func classifyHTTP(status int, err error) error {
+	if status == 429 || status >= 500 {
+		return Transient(err)
+	}
+	return Permanent(err)
+}
@@ -0,0 +1,68 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TradeFill is one execution reported by the venue.
+type TradeFill struct {
+	ID     string
+	Symbol string
+	Side   Side
+	Qty    float64
+	Price  float64
+	Time   time.Time
+}
+
+// FillLister is implemented by venues that can list an account's recent fills
+// (newest first), e.g. to rebuild an entry price after a restart.
+type FillLister interface {
+	RecentFills(symbol string) ([]TradeFill, error)
+}
+
+// RecentFills returns the latest fills on symbol (GET /fills?product_id=),
+// newest first, up to the venue's default page size.
+func (c *CoinbaseLimits) RecentFills(symbol string) ([]TradeFill, error) {
+	path := "/fills?product_id=" + symbol
+	req, err := http.NewRequest(http.MethodGet, c.apiBase+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sign(req, path, nil); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, Transient(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return nil, classifyHTTP(resp.StatusCode, fmt.Errorf("fills http %d: %s", resp.StatusCode, strings.TrimSpace(string(raw))))
+	}
+	var out []struct {
+		TradeID   int64     `json:"trade_id"`
+		ProductID string    `json:"product_id"`
+		Side      string    `json:"side"`
+		Size      string    `json:"size"`
+		Price     string    `json:"price"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("fills response: %w", err)
+	}
+	fills := make([]TradeFill, 0, len(out))
+	for _, f := range out {
+		tf := TradeFill{ID: strconv.FormatInt(f.TradeID, 10), Symbol: f.ProductID, Side: Side(strings.ToUpper(f.Side)), Time: f.CreatedAt}
+		tf.Qty, _ = strconv.ParseFloat(f.Size, 64)
+		tf.Price, _ = strconv.ParseFloat(f.Price, 64)
+		fills = append(fills, tf)
+	}
+	return fills, nil
+}
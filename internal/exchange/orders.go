@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrderStatus is a venue's view of a placed order: what actually filled and at
+// what average price, which for a market order can differ from the request.
+type OrderStatus struct {
+	ID        string
+	Symbol    string
+	Side      Side
+	FilledQty float64
+	AvgPrice  float64 // average fill price (0 until something fills)
+	Status    string  // venue status, e.g. "pending", "open", "done"
+	Done      bool    // no further fills will happen
+}
+
+// OrderGetter is implemented by venues that can look up an order by ID.
+type OrderGetter interface {
+	GetOrder(id string) (OrderStatus, error)
+}
+
+// FillConfirmer is implemented by venues that wait for a market order to
+// complete and keep the confirmed fill for the caller to collect once.
+type FillConfirmer interface {
+	Confirmed(clientOrderID string) (OrderStatus, bool)
+}
+
+// SetFillTimeout bounds how long PlaceMarketID polls for a market order to
+// complete (default 10s; <= 0 disables polling).
+func (c *CoinbaseLimits) SetFillTimeout(d time.Duration) { c.fillTimeout = d }
+
+// GetOrder fetches an order (GET /orders/<id>); id may also be
+// "client:<client_oid>" to look it up by client order ID.
+func (c *CoinbaseLimits) GetOrder(id string) (OrderStatus, error) {
+	path := "/orders/" + id
+	req, err := http.NewRequest(http.MethodGet, c.apiBase+path, nil)
+	if err != nil {
+		return OrderStatus{}, err
+	}
+	if err := c.sign(req, path, nil); err != nil {
+		return OrderStatus{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return OrderStatus{}, Transient(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return OrderStatus{}, classifyHTTP(resp.StatusCode, fmt.Errorf("get order http %d: %s", resp.StatusCode, strings.TrimSpace(string(raw))))
+	}
+	var out struct {
+		ID            string `json:"id"`
+		ProductID     string `json:"product_id"`
+		Side          string `json:"side"`
+		FilledSize    string `json:"filled_size"`
+		ExecutedValue string `json:"executed_value"`
+		Status        string `json:"status"`
+		Settled       bool   `json:"settled"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return OrderStatus{}, fmt.Errorf("get order response: %w", err)
+	}
+	st := OrderStatus{
+		ID: out.ID, Symbol: out.ProductID, Side: Side(strings.ToUpper(out.Side)),
+		Status: out.Status, Done: out.Status == "done" || out.Settled,
+	}
+	st.FilledQty, _ = strconv.ParseFloat(out.FilledSize, 64)
+	if value, _ := strconv.ParseFloat(out.ExecutedValue, 64); st.FilledQty > 0 {
+		st.AvgPrice = value / st.FilledQty
+	}
+	return st, nil
+}
+
+// Confirmed returns (and forgets) the completed fill of the market order placed
+// with clientOrderID, if PlaceMarketID saw it complete.
+func (c *CoinbaseLimits) Confirmed(clientOrderID string) (OrderStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.confirmed[clientOrderID]
+	delete(c.confirmed, clientOrderID)
+	return st, ok
+}
+
+// awaitFill polls id until the order is done or the fill timeout passes, and
+// returns the last status seen.
+func (c *CoinbaseLimits) awaitFill(id string) (OrderStatus, error) {
+	deadline := time.Now().Add(c.fillTimeout)
+	for {
+		st, err := c.GetOrder(id)
+		if err == nil && st.Done {
+			return st, nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("order %s not done after %s (status %q)", id, c.fillTimeout, st.Status)
+			}
+			return st, err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
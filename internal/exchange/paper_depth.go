@@ -0,0 +1,91 @@
+package exchange
+
+import (
+	"sort"
+)
+
+// BookLevel is one price level of a level-2 order book.
+type BookLevel struct {
+	Price float64
+	Qty   float64
+}
+
+// book is the depth a paper market order walks for one symbol.
+type book struct {
+	bids, asks []BookLevel // best first
+}
+
+// syntheticDepth describes a generated book around the current mid.
+type syntheticDepth struct {
+	levels      int
+	stepBps     float64 // distance between levels (and from mid to the first level)
+	qtyPerLevel float64
+}
+
+// SetDepth replaces symbol's order book with replayed level-2 data. Market
+// orders then walk it and fill at the volume-weighted average price instead of
+// mid ± spread/slippage. Empty sides clear the book (back to the simple model).
+func (p *PaperSim) SetDepth(symbol string, bids, asks []BookLevel) {
+	b := book{bids: append([]BookLevel(nil), bids...), asks: append([]BookLevel(nil), asks...)}
+	sort.Slice(b.bids, func(i, j int) bool { return b.bids[i].Price > b.bids[j].Price })
+	sort.Slice(b.asks, func(i, j int) bool { return b.asks[i].Price < b.asks[j].Price })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(b.bids) == 0 && len(b.asks) == 0 {
+		delete(p.books, symbol)
+		return
+	}
+	if p.books == nil { p.books = map[string]book{} }
+	p.books[symbol] = b
+}
+
+// SetSyntheticDepth gives every symbol without replayed depth a generated book:
+// levels price levels per side, stepBps apart starting stepBps from the mid,
+// each holding qtyPerLevel. levels <= 0 turns it off.
+func (p *PaperSim) SetSyntheticDepth(levels int, stepBps, qtyPerLevel float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.synth = syntheticDepth{levels: levels, stepBps: stepBps, qtyPerLevel: qtyPerLevel}
+}
+
+// bookLocked returns symbol's replayed book, else a synthetic one around mid.
+func (p *PaperSim) bookLocked(symbol string, mid float64) (book, bool) {
+	if b, ok := p.books[symbol]; ok {
+		return b, true
+	}
+	s := p.synth
+	if s.levels <= 0 || s.qtyPerLevel <= 0 || mid <= 0 {
+		return book{}, false
+	}
+	var b book
+	for i := 1; i <= s.levels; i++ {
+		off := mid * s.stepBps * float64(i) / 10000
+		b.bids = append(b.bids, BookLevel{Price: mid - off, Qty: s.qtyPerLevel})
+		b.asks = append(b.asks, BookLevel{Price: mid + off, Qty: s.qtyPerLevel})
+	}
+	return b, true
+}
+
+// vwap walks the side of b a market order takes (asks for a buy, bids for a
+// sell) and returns the volume-weighted fill price of qty. Quantity beyond the
+// visible depth fills at the last level's price.
+func (b book) vwap(side Side, qty float64) (float64, bool) {
+	levels := b.asks
+	if side == Sell { levels = b.bids }
+	if len(levels) == 0 || qty <= 0 {
+		return 0, false
+	}
+	var cost, left = 0.0, qty
+	for _, l := range levels {
+		n := l.Qty
+		if n > left { n = left }
+		cost += n * l.Price
+		if left -= n; left <= 0 {
+			break
+		}
+	}
+	if left > 0 {
+		cost += left * levels[len(levels)-1].Price
+	}
+	return cost / qty, true
+}
@@ -0,0 +1,453 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PriceUpdater is implemented by simulated venues fed from an external price stream.
+type PriceUpdater interface {
+	UpdatePrice(symbol string, price float64)
+}
+
+// SelfTestPlacer is implemented by venues that can fill a synthetic health-check
+// order (see guards.Canary) without booking it as one of the bot's trades.
+type SelfTestPlacer interface {
+	PlaceSelfTest(symbol string, side Side, qty float64) (Order, error)
+}
+
+// PaperSim wraps the paper engine with execution frictions it does not model
+// itself. Costs are tracked as a cash adjustment and reflected in
+// Account().EquityUSD, so PnL and the daily loss cap see them.
+type PaperSim struct {
+	Exchange
+	feed PriceUpdater
+
+	mu     sync.Mutex
+	adjUSD float64            // net cash adjustment vs the inner engine (negative = costs)
+	last   map[string]float64 // latest price per symbol
+	lastAt map[string]time.Time // when each latest price arrived
+	rets   map[string][]float64
+
+	// volatility-derived synthetic spread (off when volMult == 0)
+	volMult     float64
+	minSpreadBp float64
+	maxSpreadBp float64
+	volLookback int
+
+	feeBps      float64 // charged on every fill's notional
+	slippageBps float64 // market orders fill this much worse than mid
+	latency     time.Duration // mean delay before a market order fills (0 = instant)
+
+	// level-2 depth market orders walk instead of spread/slippage (see SetDepth)
+	books     map[string]book
+	synth     syntheticDepth
+	confirmed map[string]OrderStatus // depth fills by client order ID (Confirmed)
+
+	// resting limit orders, filled by UpdatePrice once a tick crosses the limit
+	orders  []LimitOrder
+	orderID int
+	runID   string // distinguishes order IDs from a previous run's (the ledger remembers fill IDs)
+	resting []TradeFill // resting fills not yet collected by TakeRestingFills
+
+	// FIFO lots per symbol and the PnL realized by matching sells against them
+	lots     map[string][]lot
+	realized map[string]float64
+	closed   []RoundTrip // matched lot closes, oldest first (at most maxClosedTrades)
+	selfTest map[string]lot // open self-test qty per symbol, at its average mid (PlaceSelfTest)
+
+	// market orders by client order ID (PlaceMarketID), so retries are no-ops
+	idMu       sync.Mutex
+	byClientID map[string]Order
+	clientIDs  []string // keys of byClientID, oldest first (at most maxClientIDs)
+}
+
+type lot struct{ qty, price float64 }
+
+// RoundTrip is the part of a buy lot closed by a sell, matched first-in
+// first-out, with the PnL it realized at mid prices.
+type RoundTrip struct {
+	Symbol     string
+	Qty        float64
+	EntryPrice float64
+	ExitPrice  float64
+	PnLUSD     float64
+}
+
+const (
+	maxClosedTrades = 10000 // oldest round-trips are dropped beyond this
+	maxClientIDs    = 1000  // client order IDs remembered for retries; oldest are forgotten
+)
+
+// NewPaperSim wraps inner; feed receives the piped prices (normally the same *Paper).
+func NewPaperSim(inner Exchange, feed PriceUpdater) *PaperSim {
+	return &PaperSim{Exchange: inner, feed: feed, last: map[string]float64{}, rets: map[string][]float64{},
+		lots: map[string][]lot{}, realized: map[string]float64{},
+		runID: strconv.FormatInt(time.Now().Unix(), 36)}
+}
+
+// SetVolSpread enables a synthetic spread of mult × realized vol (over lookback
+// ticks), clamped to [minBp, maxBp]; market orders pay half of it.
+func (p *PaperSim) SetVolSpread(mult, minBp, maxBp float64, lookback int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if lookback < 2 { lookback = 50 }
+	p.volMult, p.minSpreadBp, p.maxSpreadBp, p.volLookback = mult, minBp, maxBp, lookback
+}
+
+// SetCosts sets the per-fill fee and the market-order slippage, both in basis
+// points of notional. A buy fills at price×(1+slippage), a sell at price×(1−slippage).
+func (p *PaperSim) SetCosts(feeBps, slippageBps float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.feeBps, p.slippageBps = feeBps, slippageBps
+}
+
+// SetLatency delays every market order by about mean (uniformly within ±50%)
+// before it fills, so it executes at the price of whatever tick has arrived by
+// then rather than the one the decision saw. Zero fills instantly.
+func (p *PaperSim) SetLatency(mean time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = mean
+}
+
+// delay sleeps for the simulated order latency, if any.
+func (p *PaperSim) delay() {
+	p.mu.Lock()
+	d := p.latency
+	p.mu.Unlock()
+	if d <= 0 { return }
+	time.Sleep(d/2 + time.Duration(rand.Int63n(int64(d)+1)))
+}
+
+// UpdatePrice records the tick for the simulation and forwards it to the engine.
+func (p *PaperSim) UpdatePrice(symbol string, price float64) {
+	p.mu.Lock()
+	if prev := p.last[symbol]; prev > 0 && p.volLookback > 0 {
+		r := append(p.rets[symbol], (price-prev)/prev)
+		if len(r) > p.volLookback { r = r[len(r)-p.volLookback:] }
+		p.rets[symbol] = r
+	}
+	p.last[symbol] = price
+	if p.lastAt == nil { p.lastAt = map[string]time.Time{} }
+	p.lastAt[symbol] = time.Now()
+	p.mu.Unlock()
+	p.feed.UpdatePrice(symbol, price)
+	p.fillCrossed(symbol, price)
+}
+
+// LastTickAge is how long ago the piped live feed last updated symbol's price.
+func (p *PaperSim) LastTickAge(symbol string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return tickAge(p.lastAt, symbol)
+}
+
+// PlaceLimit rests a GTC limit order; a buy fills once a tick trades at or below
+// the limit, a sell at or above it, at the limit price. Fills pay no spread (the
+// order is the maker) and are reported through TakeRestingFills.
+func (p *PaperSim) PlaceLimit(symbol string, side Side, qty, limitPrice float64) (LimitOrder, error) {
+	if qty <= 0 || limitPrice <= 0 {
+		return LimitOrder{}, Permanent(fmt.Errorf("limit order needs qty and price > 0 (qty=%v price=%v)", qty, limitPrice))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orderID++
+	o := LimitOrder{ID: fmt.Sprintf("paper-limit-%s-%d", p.runID, p.orderID), Symbol: symbol, Side: side, Qty: qty, LimitPrice: limitPrice, Status: "open"}
+	p.orders = append(p.orders, o)
+	return o, nil
+}
+
+// PlaceStop rests a stop order: a sell-stop becomes a market sell once a tick is
+// at or below stopPrice, a buy-stop a market buy at or above it. Triggered stops
+// fill through PlaceMarket, so spread, slippage and fees apply.
+func (p *PaperSim) PlaceStop(symbol string, side Side, qty, stopPrice float64) (LimitOrder, error) {
+	if qty <= 0 || stopPrice <= 0 {
+		return LimitOrder{}, Permanent(fmt.Errorf("stop order needs qty and price > 0 (qty=%v price=%v)", qty, stopPrice))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orderID++
+	o := LimitOrder{ID: fmt.Sprintf("paper-stop-%s-%d", p.runID, p.orderID), Symbol: symbol, Side: side, Qty: qty, LimitPrice: stopPrice, Stop: true, Status: "open"}
+	p.orders = append(p.orders, o)
+	return o, nil
+}
+
+// CancelOrder drops one resting order by ID.
+func (p *PaperSim) CancelOrder(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, o := range p.orders {
+		if o.ID == id {
+			p.orders = append(p.orders[:i], p.orders[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("order %s not open", id)
+}
+
+// CancelAll drops every resting order (limit and stop) on symbol.
+func (p *PaperSim) CancelAll(symbol string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rest := p.orders[:0]
+	for _, o := range p.orders {
+		if o.Symbol != symbol { rest = append(rest, o) }
+	}
+	p.orders = rest
+	return nil
+}
+
+// OpenOrders returns the resting limit and stop orders that have not filled yet.
+func (p *PaperSim) OpenOrders() []LimitOrder {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]LimitOrder(nil), p.orders...)
+}
+
+// fillCrossed executes every resting order on symbol that price has crossed or
+// triggered. An order the engine rejects (e.g. insufficient cash) stays open.
+func (p *PaperSim) fillCrossed(symbol string, price float64) {
+	p.mu.Lock()
+	var hit []LimitOrder
+	rest := p.orders[:0]
+	for _, o := range p.orders {
+		if o.Symbol == symbol && crossed(o, price) {
+			hit = append(hit, o)
+			continue
+		}
+		rest = append(rest, o)
+	}
+	p.orders = rest
+	p.mu.Unlock()
+
+	for _, o := range hit {
+		if o.Stop {
+			// the venue triggers the stop itself: no order latency to simulate
+			_, vwap, err := p.placeMarket(o.Symbol, o.Side, o.Qty)
+			p.mu.Lock()
+			if err != nil {
+				p.orders = append(p.orders, o)
+			} else {
+				px := price // a market fill, booked at mid like the caller's own
+				if vwap > 0 { px = vwap }
+				p.noteRestingLocked(o, px)
+			}
+			p.mu.Unlock()
+			continue
+		}
+		_, err := p.Exchange.PlaceMarket(o.Symbol, o.Side, o.Qty)
+		p.mu.Lock()
+		if err != nil {
+			p.orders = append(p.orders, o)
+		} else {
+			// a resting order fills at its limit; the engine filled at the tick,
+			// which is at or through the limit, so book the difference back
+			p.adjUSD -= o.Qty * math.Abs(price-o.LimitPrice)
+			p.adjUSD -= o.Qty * o.LimitPrice * p.feeBps / 10000 // limit fills pay the fee, no slippage
+			p.matchLocked(o.Symbol, o.Side, o.Qty, o.LimitPrice)
+			p.noteRestingLocked(o, o.LimitPrice)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// noteRestingLocked queues the fill of resting order o for TakeRestingFills.
+func (p *PaperSim) noteRestingLocked(o LimitOrder, price float64) {
+	p.resting = append(p.resting, TradeFill{ID: o.ID, Symbol: o.Symbol, Side: o.Side, Qty: o.Qty, Price: price, Time: time.Now()})
+}
+
+// TakeRestingFills returns (and forgets) the resting orders filled since the
+// last call, oldest first, so the caller can book them like its own orders.
+func (p *PaperSim) TakeRestingFills() []TradeFill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := p.resting
+	p.resting = nil
+	return out
+}
+
+// crossed: a limit buy fills at or below its price and a limit sell at or above;
+// stops are the reverse (sell-stop at or below, buy-stop at or above).
+func crossed(o LimitOrder, price float64) bool {
+	if o.Stop {
+		return (o.Side == Sell && price <= o.LimitPrice) || (o.Side == Buy && price >= o.LimitPrice)
+	}
+	return (o.Side == Buy && price <= o.LimitPrice) || (o.Side == Sell && price >= o.LimitPrice)
+}
+
+// SpreadBps is the current synthetic spread for symbol (0 when disabled).
+func (p *PaperSim) SpreadBps(symbol string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.spreadBpsLocked(symbol)
+}
+
+func (p *PaperSim) PlaceMarket(symbol string, side Side, qty float64) (Order, error) {
+	p.delay()
+	ord, _, err := p.placeMarket(symbol, side, qty)
+	return ord, err
+}
+
+// placeMarket fills at mid ± (half-spread + slippage), or at the VWAP of the
+// book when depth is available, plus the fee. It returns the VWAP of a depth
+// fill (0 for the simple model).
+func (p *PaperSim) placeMarket(symbol string, side Side, qty float64) (Order, float64, error) {
+	ord, err := p.Exchange.PlaceMarket(symbol, side, qty)
+	// the engine is in-process, so a refusal is always about the order itself
+	// (e.g. insufficient funds) and must not count toward the breaker
+	if err != nil { return ord, 0, Permanent(err) }
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	mid := p.last[symbol]
+	var vwap float64
+	if b, ok := p.bookLocked(symbol, mid); ok {
+		vwap, _ = b.vwap(side, qty)
+	}
+	if vwap > 0 {
+		// the engine filled at mid: book the walk through the depth as a cost
+		adverse := vwap - mid
+		if side == Sell { adverse = mid - vwap }
+		p.adjUSD -= qty*adverse + qty*vwap*p.feeBps/10000
+	} else {
+		// costs whichever the side
+		notional := qty * mid
+		p.adjUSD -= notional * (p.spreadBpsLocked(symbol)/2 + p.slippageBps + p.feeBps) / 10000
+	}
+	p.matchLocked(symbol, side, qty, mid)
+	return ord, vwap, nil
+}
+
+// PlaceSelfTest fills a health-check order on the engine at mid, so the account
+// position moves, but keeps it out of the FIFO lots, RealizedPnL and the cost
+// model: a canary round trip leaves the bot's realized PnL and equity as they
+// were, even if the price moved while it was open.
+func (p *PaperSim) PlaceSelfTest(symbol string, side Side, qty float64) (Order, error) {
+	ord, err := p.Exchange.PlaceMarket(symbol, side, qty)
+	if err != nil { return ord, Permanent(err) }
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.selfTest == nil { p.selfTest = map[string]lot{} }
+	mid, held := p.last[symbol], p.selfTest[symbol]
+	if side == Buy {
+		held.price = (held.price*held.qty + mid*qty) / (held.qty + qty)
+		held.qty += qty
+	} else {
+		q := math.Min(qty, held.qty)
+		p.adjUSD -= q * (mid - held.price) // the engine booked the move as cash
+		held.qty -= q
+	}
+	if held.qty <= 1e-12 {
+		delete(p.selfTest, symbol)
+	} else {
+		p.selfTest[symbol] = held
+	}
+	return ord, nil
+}
+
+// Confirmed returns (and forgets) the depth fill of the market order placed with
+// clientOrderID; orders filled by the simple model have none.
+func (p *PaperSim) Confirmed(clientOrderID string) (OrderStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st, ok := p.confirmed[clientOrderID]
+	delete(p.confirmed, clientOrderID)
+	return st, ok
+}
+
+// RealizedPnL is the PnL realized on symbol so far, matching sells against buys
+// first-in first-out at the mid price (spread costs show in equity, not here).
+func (p *PaperSim) RealizedPnL(symbol string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.realized[symbol]
+}
+
+// ClosedTrades returns the round-trips closed so far, oldest first. A sell that
+// spans several lots yields one entry per lot.
+func (p *PaperSim) ClosedTrades() []RoundTrip {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]RoundTrip(nil), p.closed...)
+}
+
+// matchLocked adds a buy as a new lot, or closes the oldest lots for a sell.
+func (p *PaperSim) matchLocked(symbol string, side Side, qty, price float64) {
+	if qty <= 0 || price <= 0 { return }
+	if side == Buy {
+		p.lots[symbol] = append(p.lots[symbol], lot{qty: qty, price: price})
+		return
+	}
+	lots := p.lots[symbol]
+	for qty > 0 && len(lots) > 0 {
+		n := math.Min(qty, lots[0].qty)
+		pnl := n * (price - lots[0].price)
+		p.realized[symbol] += pnl
+		p.closed = append(p.closed, RoundTrip{Symbol: symbol, Qty: n, EntryPrice: lots[0].price, ExitPrice: price, PnLUSD: pnl})
+		if len(p.closed) > maxClosedTrades { p.closed = p.closed[len(p.closed)-maxClosedTrades:] }
+		qty -= n
+		if lots[0].qty -= n; lots[0].qty <= 1e-12 {
+			lots = lots[1:]
+		}
+	}
+	p.lots[symbol] = lots
+}
+
+func (p *PaperSim) Account() (Account, error) {
+	acct, err := p.Exchange.Account()
+	if err != nil { return acct, err }
+	p.mu.Lock()
+	acct.EquityUSD += p.adjUSD
+	p.mu.Unlock()
+	return acct, nil
+}
+
+// Deposit adds usd of external cash (a funding event), raising equity by usd.
+func (p *PaperSim) Deposit(usd float64) {
+	if usd <= 0 { return }
+	p.mu.Lock()
+	p.adjUSD += usd
+	p.mu.Unlock()
+}
+
+// Withdraw removes usd of cash, lowering equity by usd. It fails, changing
+// nothing, if cash (equity less positions at their latest price) is short.
+func (p *PaperSim) Withdraw(usd float64) error {
+	if usd <= 0 { return nil }
+	acct, err := p.Account()
+	if err != nil { return err }
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cash := acct.EquityUSD
+	for sym, pos := range acct.Positions {
+		cash -= pos.BaseQty * p.last[sym]
+	}
+	if usd > cash+1e-9 {
+		return Permanent(fmt.Errorf("withdraw %.2f exceeds cash %.2f", usd, cash))
+	}
+	p.adjUSD -= usd
+	return nil
+}
+
+func (p *PaperSim) spreadBpsLocked(symbol string) float64 {
+	if p.volMult <= 0 { return 0 }
+	r := p.rets[symbol]
+	var vol float64
+	if n := len(r); n >= 2 {
+		mean := 0.0
+		for _, x := range r { mean += x }
+		mean /= float64(n)
+		var ss float64
+		for _, x := range r { ss += (x - mean) * (x - mean) }
+		vol = math.Sqrt(ss / float64(n))
+	}
+	bp := p.volMult * vol * 10000
+	if bp < p.minSpreadBp { bp = p.minSpreadBp }
+	if p.maxSpreadBp > 0 && bp > p.maxSpreadBp { bp = p.maxSpreadBp }
+	return bp
+}
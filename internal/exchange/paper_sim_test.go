@@ -0,0 +1,237 @@
+package exchange
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// fakeEngine stands in for the paper engine: it fills market orders at the
+// last piped price against cash and counts them, or refuses them all with err.
+type fakeEngine struct {
+	orders int
+	err    error
+	cash   float64
+	pos    map[string]float64
+	last   map[string]float64
+}
+
+func (f *fakeEngine) BestBidAsk(symbol string) (float64, float64, error) { return 0, 0, nil }
+func (f *fakeEngine) Account() (Account, error) {
+	acct := Account{EquityUSD: f.cash, Positions: map[string]Position{}}
+	for sym, q := range f.pos {
+		acct.EquityUSD += q * f.last[sym]
+		acct.Positions[sym] = Position{BaseQty: q}
+	}
+	return acct, nil
+}
+func (f *fakeEngine) StreamPrices(symbol string, out chan<- Ticker) (func(), error) {
+	return func() {}, nil
+}
+func (f *fakeEngine) PlaceMarket(symbol string, side Side, qty float64) (Order, error) {
+	if f.err != nil {
+		return Order{}, f.err
+	}
+	f.orders++
+	if side == Sell { qty = -qty }
+	f.pos[symbol] += qty
+	f.cash -= qty * f.last[symbol]
+	return Order{}, nil
+}
+func (f *fakeEngine) UpdatePrice(symbol string, price float64) { f.last[symbol] = price }
+
+func newTestSim() (*PaperSim, *fakeEngine) {
+	eng := &fakeEngine{pos: map[string]float64{}, last: map[string]float64{}}
+	return NewPaperSim(eng, eng), eng
+}
+
+func TestPaperLimitFillsAtLimitAndIsReported(t *testing.T) {
+	p, eng := newTestSim()
+	p.UpdatePrice("BTC-USD", 105)
+	o, err := p.PlaceLimit("BTC-USD", Buy, 2, 100)
+	if err != nil {
+		t.Fatalf("PlaceLimit: %v", err)
+	}
+	p.UpdatePrice("BTC-USD", 101)
+	if eng.orders != 0 || len(p.TakeRestingFills()) != 0 {
+		t.Fatalf("limit buy at 100 filled on a 101 tick")
+	}
+	p.UpdatePrice("BTC-USD", 97) // gaps through the limit
+	fills := p.TakeRestingFills()
+	if len(fills) != 1 {
+		t.Fatalf("got %d resting fills, want 1", len(fills))
+	}
+	if f := fills[0]; f.ID != o.ID || f.Side != Buy || f.Qty != 2 || f.Price != 100 {
+		t.Errorf("fill = %+v, want %s BUY 2 @ 100", f, o.ID)
+	}
+	if len(p.TakeRestingFills()) != 0 {
+		t.Errorf("resting fill reported twice")
+	}
+	if len(p.OpenOrders()) != 0 {
+		t.Errorf("filled order still open")
+	}
+
+	// the lot is booked at the limit, not at the 97 tick
+	p.PlaceMarket("BTC-USD", Sell, 2)
+	trips := p.ClosedTrades()
+	if len(trips) != 1 || trips[0].EntryPrice != 100 {
+		t.Errorf("round trips = %+v, want one entered at 100", trips)
+	}
+}
+
+func TestPaperStopFiresOnceOnDescendingPrices(t *testing.T) {
+	p, eng := newTestSim()
+	p.UpdatePrice("BTC-USD", 110)
+	p.PlaceMarket("BTC-USD", Buy, 1)
+	o, err := p.PlaceStop("BTC-USD", Sell, 1, 100)
+	if err != nil {
+		t.Fatalf("PlaceStop: %v", err)
+	}
+	if open := p.OpenOrders(); len(open) != 1 || !open[0].Stop {
+		t.Fatalf("open orders = %+v, want the stop", open)
+	}
+	fired := -1
+	for i, px := range []float64{108, 105, 101, 100.5, 99, 98, 97, 95} {
+		before := eng.orders
+		p.UpdatePrice("BTC-USD", px)
+		fills := p.TakeRestingFills()
+		if eng.orders-before != len(fills) {
+			t.Fatalf("tick %v: %d engine orders but %d reported fills", px, eng.orders-before, len(fills))
+		}
+		if len(fills) == 0 {
+			continue
+		}
+		if fired >= 0 {
+			t.Fatalf("stop fired again at %v", px)
+		}
+		fired = i
+		if f := fills[0]; f.ID != o.ID || f.Side != Sell || f.Qty != 1 || f.Price != px {
+			t.Errorf("fill = %+v, want %s SELL 1 @ %v", f, o.ID, px)
+		}
+	}
+	if fired != 4 {
+		t.Errorf("stop fired at tick %d, want 4 (the first at or below 100)", fired)
+	}
+	if len(p.OpenOrders()) != 0 {
+		t.Errorf("triggered stop still open")
+	}
+}
+
+func TestPaperRefusalIsPermanent(t *testing.T) {
+	p, eng := newTestSim()
+	eng.err = errors.New("insufficient funds")
+	p.UpdatePrice("BTC-USD", 100)
+	if _, err := p.PlaceMarket("BTC-USD", Buy, 1); err == nil || IsTransient(err) {
+		t.Errorf("engine refusal = %v, want a permanent error", err)
+	}
+	if _, err := p.PlaceLimit("BTC-USD", Buy, 0, 100); err == nil || IsTransient(err) {
+		t.Errorf("zero-qty limit = %v, want a permanent error", err)
+	}
+}
+
+func TestPaperDepositWithdraw(t *testing.T) {
+	p, eng := newTestSim()
+	eng.cash = 1000
+	equity := func() float64 {
+		acct, err := p.Account()
+		if err != nil {
+			t.Fatalf("Account: %v", err)
+		}
+		return acct.EquityUSD
+	}
+	p.Deposit(500)
+	if e := equity(); e != 1500 {
+		t.Fatalf("equity after deposit = %v, want 1500", e)
+	}
+	p.UpdatePrice("BTC-USD", 100)
+	p.PlaceMarket("BTC-USD", Buy, 2)
+	p.UpdatePrice("BTC-USD", 110)
+	if e := equity(); e != 1520 {
+		t.Fatalf("equity after buying 2 @ 100, marked at 110 = %v, want 1520", e)
+	}
+	// cash is 1500 - 200 = 1300; the 220 of BTC can't be withdrawn
+	if err := p.Withdraw(1400); err == nil {
+		t.Errorf("withdrew 1400 with 1300 cash")
+	}
+	if e := equity(); e != 1520 {
+		t.Errorf("refused withdrawal changed equity to %v", e)
+	}
+	if err := p.Withdraw(300); err != nil {
+		t.Fatalf("Withdraw(300): %v", err)
+	}
+	if e := equity(); e != 1220 {
+		t.Errorf("equity after withdrawing 300 = %v, want 1220", e)
+	}
+}
+
+func TestPaperVolSpreadWidensWithVolatility(t *testing.T) {
+	fillCost := func(prices []float64) (cost, spreadBp float64) {
+		p, eng := newTestSim()
+		eng.cash = 10000
+		p.SetVolSpread(1, 0, 0, 10)
+		for _, px := range prices {
+			p.UpdatePrice("BTC-USD", px)
+		}
+		spreadBp = p.SpreadBps("BTC-USD")
+		p.PlaceMarket("BTC-USD", Buy, 10)
+		acct, _ := p.Account()
+		return 10000 - acct.EquityUSD, spreadBp
+	}
+	calmCost, calmBp := fillCost([]float64{100, 100.01, 100, 100.01, 100})
+	wildCost, wildBp := fillCost([]float64{100, 103, 97, 103, 100})
+	if wildBp <= calmBp {
+		t.Fatalf("spread %vbp in the volatile market, %vbp in the calm one", wildBp, calmBp)
+	}
+	if wildCost <= calmCost {
+		t.Errorf("buying 10 cost %v in the volatile market, %v in the calm one; want a worse fill", wildCost, calmCost)
+	}
+	// market orders pay half the spread
+	if want := 1000 * wildBp / 2 / 10000; math.Abs(wildCost-want) > 1e-9 {
+		t.Errorf("volatile fill cost %v, want half the %vbp spread = %v", wildCost, wildBp, want)
+	}
+}
+
+func TestPaperRealizedPnLMatchesFIFO(t *testing.T) {
+	p, _ := newTestSim()
+	p.UpdatePrice("BTC-USD", 100)
+	p.PlaceMarket("BTC-USD", Buy, 2)
+	p.UpdatePrice("BTC-USD", 110)
+	p.PlaceMarket("BTC-USD", Buy, 1)
+	p.UpdatePrice("BTC-USD", 120)
+	p.PlaceMarket("BTC-USD", Sell, 2) // closes the first lot: 2 × (120-100)
+	if pnl := p.RealizedPnL("BTC-USD"); pnl != 40 {
+		t.Fatalf("realized after closing the 100 lot = %v, want 40", pnl)
+	}
+	p.UpdatePrice("BTC-USD", 105)
+	p.PlaceMarket("BTC-USD", Sell, 1) // closes the 110 lot at a loss of 5
+	if pnl := p.RealizedPnL("BTC-USD"); pnl != 35 {
+		t.Errorf("realized after both closes = %v, want 35", pnl)
+	}
+	if trips := p.ClosedTrades(); len(trips) != 2 || trips[1].EntryPrice != 110 || trips[1].PnLUSD != -5 {
+		t.Errorf("round trips = %+v, want the second entered at 110 for -5", trips)
+	}
+}
+
+func TestPaperRoundTripCostsTwoFeesAndSlippage(t *testing.T) {
+	const feeBps, slipBps = 10.0, 5.0
+	p, eng := newTestSim()
+	eng.cash = 10000
+	p.SetCosts(feeBps, slipBps)
+	p.UpdatePrice("BTC-USD", 1000)
+	p.PlaceMarket("BTC-USD", Buy, 2)
+	p.UpdatePrice("BTC-USD", 1100)
+	p.PlaceMarket("BTC-USD", Sell, 2)
+
+	acct, err := p.Account()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each fill pays fee + slippage on its own notional: 2000 in, 2200 out
+	costs := (2000 + 2200) * (feeBps + slipBps) / 10000
+	if want := 10000 + 200 - costs; math.Abs(acct.EquityUSD-want) > 1e-9 {
+		t.Errorf("equity after the round trip = %v, want %v (200 gained less %v costs)", acct.EquityUSD, want, costs)
+	}
+	if pnl := p.RealizedPnL("BTC-USD"); pnl != 200 {
+		t.Errorf("realized = %v, want 200 (costs show in equity only)", pnl)
+	}
+}
@@ -0,0 +1,111 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProductMeta is the venue's trading metadata for one symbol.
+type ProductMeta struct {
+	Symbol          string
+	BaseIncrement   float64 // qty step
+	QuoteIncrement  float64 // price tick
+	BaseMinSize     float64 // minimum order qty (0 = not published)
+	Status          string
+	PostOnly        bool
+	LimitOnly       bool
+	CancelOnly      bool
+	TradingDisabled bool
+}
+
+// Tradable returns a descriptive error when market orders cannot be placed.
+func (p ProductMeta) Tradable() error {
+	switch {
+	case p.TradingDisabled || (p.Status != "" && p.Status != "online"):
+		return fmt.Errorf("%s is not tradable (status=%q, trading_disabled=%v)", p.Symbol, p.Status, p.TradingDisabled)
+	case p.CancelOnly:
+		return fmt.Errorf("%s is cancel-only", p.Symbol)
+	case p.PostOnly:
+		return fmt.Errorf("%s is post-only (no market orders)", p.Symbol)
+	case p.LimitOnly:
+		return fmt.Errorf("%s is limit-only (no market orders)", p.Symbol)
+	}
+	return nil
+}
+
+// FetchCoinbaseProduct reads public product metadata (GET {apiBase}/products/{symbol}).
+// client may be nil.
+func FetchCoinbaseProduct(client *http.Client, apiBase, symbol string) (ProductMeta, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(strings.TrimRight(apiBase, "/") + "/products/" + symbol)
+	if err != nil {
+		return ProductMeta{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ProductMeta{}, fmt.Errorf("%s: unknown product on this venue", symbol)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ProductMeta{}, fmt.Errorf("%s: product lookup http %d", symbol, resp.StatusCode)
+	}
+	var raw struct {
+		ID              string `json:"id"`
+		BaseIncrement   string `json:"base_increment"`
+		QuoteIncrement  string `json:"quote_increment"`
+		BaseMinSize     string `json:"base_min_size"`
+		Status          string `json:"status"`
+		PostOnly        bool   `json:"post_only"`
+		LimitOnly       bool   `json:"limit_only"`
+		CancelOnly      bool   `json:"cancel_only"`
+		TradingDisabled bool   `json:"trading_disabled"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ProductMeta{}, fmt.Errorf("%s: decode product: %w", symbol, err)
+	}
+	num := func(s string) float64 { v, _ := strconv.ParseFloat(s, 64); return v }
+	return ProductMeta{
+		Symbol:          symbol,
+		BaseIncrement:   num(raw.BaseIncrement),
+		QuoteIncrement:  num(raw.QuoteIncrement),
+		BaseMinSize:     num(raw.BaseMinSize),
+		Status:          raw.Status,
+		PostOnly:        raw.PostOnly,
+		LimitOnly:       raw.LimitOnly,
+		CancelOnly:      raw.CancelOnly,
+		TradingDisabled: raw.TradingDisabled,
+	}, nil
+}
+
+// ProductCache memoizes metadata lookups so startup validation and the sizing
+// helpers share one fetch per symbol.
+type ProductCache struct {
+	mu    sync.Mutex
+	fetch func(symbol string) (ProductMeta, error)
+	m     map[string]ProductMeta
+}
+
+func NewProductCache(fetch func(symbol string) (ProductMeta, error)) *ProductCache {
+	return &ProductCache{fetch: fetch, m: map[string]ProductMeta{}}
+}
+
+// Get returns cached metadata, fetching it on first use.
+func (c *ProductCache) Get(symbol string) (ProductMeta, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.m[symbol]; ok {
+		return p, nil
+	}
+	p, err := c.fetch(symbol)
+	if err != nil {
+		return ProductMeta{}, err
+	}
+	c.m[symbol] = p
+	return p, nil
+}
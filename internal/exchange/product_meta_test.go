@@ -0,0 +1,47 @@
+package exchange
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchCoinbaseProductDelisted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/products/BTC-USD":
+			w.Write([]byte(`{"id":"BTC-USD","base_increment":"0.00000001","quote_increment":"0.01",
+				"base_min_size":"0.0001","status":"online","trading_disabled":false}`))
+		case "/products/OLD-USD":
+			w.Write([]byte(`{"id":"OLD-USD","base_increment":"0.1","quote_increment":"0.0001",
+				"status":"delisted","trading_disabled":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	meta, err := FetchCoinbaseProduct(srv.Client(), srv.URL+"/", "BTC-USD")
+	if err != nil {
+		t.Fatalf("BTC-USD: %v", err)
+	}
+	if meta.BaseIncrement != 0.00000001 || meta.QuoteIncrement != 0.01 || meta.BaseMinSize != 0.0001 {
+		t.Errorf("BTC-USD meta = %+v", meta)
+	}
+	if err := meta.Tradable(); err != nil {
+		t.Errorf("online product not tradable: %v", err)
+	}
+
+	meta, err = FetchCoinbaseProduct(srv.Client(), srv.URL, "OLD-USD")
+	if err != nil {
+		t.Fatalf("OLD-USD: %v", err)
+	}
+	if err := meta.Tradable(); err == nil || !strings.Contains(err.Error(), "delisted") {
+		t.Errorf("delisted product: Tradable() = %v, want an error naming the status", err)
+	}
+
+	if _, err := FetchCoinbaseProduct(srv.Client(), srv.URL, "NOPE-USD"); err == nil || !strings.Contains(err.Error(), "unknown product") {
+		t.Errorf("unknown product: err = %v", err)
+	}
+}
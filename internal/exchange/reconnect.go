@@ -0,0 +1,197 @@
+package exchange
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricFeedUp         = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_price_feed_up", Help: "1 while the price stream is delivering ticks, 0 while it is down/reconnecting"})
+	metricFeedReconnects = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_price_feed_reconnects_total", Help: "Price stream reconnects after a dropped or stale connection"})
+	metricWSGaps         = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "bot_ws_gaps_total", Help: "Sequence gaps (dropped messages) seen on the price stream"}, []string{"symbol"})
+)
+
+func init() { prometheus.MustRegister(metricFeedUp, metricFeedReconnects, metricWSGaps) }
+
+// GapNotifier is implemented by streaming backends whose messages carry
+// sequence numbers: they call fn with the number of messages missed whenever
+// the sequence jumps. Feeds without sequence info rely on StaleAfter alone.
+type GapNotifier interface {
+	SetGapHandler(fn func(symbol string, missed int64))
+}
+
+// ReconnectingFeed re-establishes an inner price stream that has gone quiet. A
+// stream with no tick for StaleAfter is treated as dropped: it is stopped and
+// re-subscribed with exponential backoff (capped, with jitter), and ticks keep
+// flowing to the caller's channel.
+type ReconnectingFeed struct {
+	Exchange
+	StaleAfter time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxGap     int64 // a sequence gap of this many messages forces a resubscribe (0 = count only)
+
+	mu       sync.Mutex
+	lastTick map[string]time.Time     // arrival of the latest tick per symbol
+	kicks    map[string]chan struct{} // per-symbol resubscribe requests
+}
+
+// TickAger is implemented by feeds that know when a symbol last ticked, so
+// callers can refuse to trade on a price that has stopped updating.
+type TickAger interface {
+	LastTickAge(symbol string) time.Duration
+}
+
+// LastTickAge is how long ago symbol's latest tick arrived; it is
+// math.MaxInt64 (effectively forever) before the first one.
+func (r *ReconnectingFeed) LastTickAge(symbol string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return tickAge(r.lastTick, symbol)
+}
+
+func tickAge(last map[string]time.Time, symbol string) time.Duration {
+	t, ok := last[symbol]
+	if !ok {
+		return math.MaxInt64
+	}
+	return time.Since(t)
+}
+
+// SetGapHandler passes fn on to the wrapped backend if it reports gaps.
+func (c *CoinbaseLimits) SetGapHandler(fn func(symbol string, missed int64)) {
+	if g, ok := c.Exchange.(GapNotifier); ok { g.SetGapHandler(fn) }
+}
+
+func NewReconnectingFeed(inner Exchange, staleAfter time.Duration) *ReconnectingFeed {
+	if staleAfter <= 0 { staleAfter = 30 * time.Second }
+	r := &ReconnectingFeed{Exchange: inner, StaleAfter: staleAfter, MinBackoff: time.Second, MaxBackoff: time.Minute}
+	if g, ok := inner.(GapNotifier); ok { g.SetGapHandler(r.noteGap) }
+	return r
+}
+
+// noteGap counts a sequence gap and, when it reaches MaxGap, resubscribes the
+// symbol rather than keep feeding the strategy a series with a hole in it.
+func (r *ReconnectingFeed) noteGap(symbol string, missed int64) {
+	if missed <= 0 { return }
+	metricWSGaps.WithLabelValues(symbol).Inc()
+	log.Printf("[feed] %s: sequence gap, %d message(s) missed", symbol, missed)
+	if r.MaxGap > 0 && missed >= r.MaxGap { r.Resubscribe(symbol) }
+}
+
+// Resubscribe asks the symbol's supervisor to drop and re-establish its stream
+// now instead of waiting for it to go stale.
+func (r *ReconnectingFeed) Resubscribe(symbol string) {
+	r.mu.Lock()
+	kick := r.kicks[symbol]
+	r.mu.Unlock()
+	if kick == nil { return }
+	select {
+	case kick <- struct{}{}:
+	default: // one already pending
+	}
+}
+
+// StreamPrices connects once synchronously (so startup errors still surface),
+// then supervises the stream. The returned stop cancels any reconnect loop and
+// returns once the inner stream has been stopped.
+func (r *ReconnectingFeed) StreamPrices(symbol string, out chan<- Ticker) (func(), error) {
+	in := make(chan Ticker, 64)
+	stopInner, err := r.Exchange.StreamPrices(symbol, in)
+	if err != nil {
+		return nil, err
+	}
+	done, exited := make(chan struct{}), make(chan struct{})
+	kick := make(chan struct{}, 1)
+	r.mu.Lock()
+	if r.kicks == nil { r.kicks = map[string]chan struct{}{} }
+	r.kicks[symbol] = kick
+	r.mu.Unlock()
+	var once sync.Once
+	go r.supervise(symbol, out, in, stopInner, kick, done, exited)
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}, nil
+}
+
+func (r *ReconnectingFeed) supervise(symbol string, out chan<- Ticker, in chan Ticker, stopInner func(), kick, done, exited chan struct{}) {
+	defer close(exited)
+	defer func() {
+		if stopInner != nil { stopInner() }
+		metricFeedUp.Set(0)
+	}()
+	backoff := r.MinBackoff
+	stale := time.NewTimer(r.StaleAfter)
+	defer stale.Stop()
+	for {
+		var why string // set when the stream must be re-established
+		select {
+		case <-done:
+			return
+		case t := <-in:
+			metricFeedUp.Set(1)
+			r.mu.Lock()
+			if r.lastTick == nil { r.lastTick = map[string]time.Time{} }
+			r.lastTick[symbol] = time.Now()
+			r.mu.Unlock()
+			backoff = r.MinBackoff
+			if !stale.Stop() {
+				select {
+				case <-stale.C:
+				default:
+				}
+			}
+			stale.Reset(r.StaleAfter)
+			select {
+			case out <- t:
+			case <-done:
+				return
+			}
+		case <-kick:
+			why = "sequence gap"
+			if !stale.Stop() {
+				select {
+				case <-stale.C:
+				default:
+				}
+			}
+		case <-stale.C:
+			why = "no ticks for " + r.StaleAfter.String()
+		}
+		if why == "" {
+			continue
+		}
+		metricFeedUp.Set(0)
+		log.Printf("[feed] %s: %s, reconnecting", symbol, why)
+		stopInner()
+		stopInner = nil
+		for stopInner == nil {
+			select {
+			case <-time.After(jitter(backoff)):
+			case <-done:
+				return
+			}
+			in = make(chan Ticker, 64) // the old stream may still hold a reference
+			var err error
+			if stopInner, err = r.Exchange.StreamPrices(symbol, in); err != nil {
+				log.Printf("[feed] %s: reconnect failed: %v", symbol, err)
+				stopInner = nil
+			}
+			if backoff *= 2; backoff > r.MaxBackoff { backoff = r.MaxBackoff }
+		}
+		metricFeedReconnects.Inc()
+		stale.Reset(r.StaleAfter)
+	}
+}
+
+// jitter spreads d over [d/2, d) so many clients don't reconnect in lockstep.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 { return d }
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
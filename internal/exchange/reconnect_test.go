@@ -0,0 +1,70 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+)
+
+// streamSource is a fakeEngine whose streams send ticks as fast as they are
+// taken until stopped; stop returns once the sender has exited.
+type streamSource struct{ fakeEngine }
+
+func (s *streamSource) StreamPrices(symbol string, out chan<- Ticker) (func(), error) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for px := 1.0; ; px++ {
+			select {
+			case out <- Ticker{Symbol: symbol, Price: px}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done); <-exited }, nil
+}
+
+// The bot's shutdown order, repeated: stop every reconnecting stream, close
+// the shared channel, then drain the tick buffer. A send racing the close would
+// panic; run with -race to also catch unsynchronized access.
+func TestFeedShutdownOrdering(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		priceCh := make(chan Ticker, 8)
+		buf := NewTickBuffer(4, DropOldest)
+		go buf.Pump(priceCh)
+		got := make(chan struct{}, 1)
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			for range buf.C() {
+				select {
+				case got <- struct{}{}:
+				default:
+				}
+			}
+		}()
+
+		rf := NewReconnectingFeed(&streamSource{}, time.Second)
+		var stops []func()
+		for _, sym := range []string{"BTC-USD", "ETH-USD"} {
+			stop, err := rf.StreamPrices(sym, priceCh)
+			if err != nil {
+				t.Fatalf("StreamPrices: %v", err)
+			}
+			stops = append(stops, stop)
+		}
+		select {
+		case <-got:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d: no tick reached the consumer", i)
+		}
+
+		for _, stop := range stops { stop() }
+		close(priceCh)
+		select {
+		case <-drained:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d: consumer did not finish after the close", i)
+		}
+	}
+}
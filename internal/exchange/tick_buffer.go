@@ -0,0 +1,59 @@
+package exchange
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var metricTicksDropped = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_ticks_dropped_total", Help: "Ticks dropped by the drop-oldest tick buffer"})
+
+func init() { prometheus.MustRegister(metricTicksDropped) }
+
+// OverflowPolicy says what a full TickBuffer does with a new tick.
+type OverflowPolicy string
+
+const (
+	DropOldest OverflowPolicy = "drop_oldest" // discard the oldest tick, latest price wins (default)
+	Block      OverflowPolicy = "block"       // wait for the consumer (back-pressures the feed)
+)
+
+// TickBuffer decouples a price stream from a possibly slow consumer with an
+// explicit overflow policy. Feed it with Pump and read from C.
+type TickBuffer struct {
+	ch     chan Ticker
+	policy OverflowPolicy
+}
+
+func NewTickBuffer(size int, policy OverflowPolicy) *TickBuffer {
+	if size < 1 { size = 1 }
+	if policy != Block { policy = DropOldest }
+	return &TickBuffer{ch: make(chan Ticker, size), policy: policy}
+}
+
+// C is the consumer side.
+func (b *TickBuffer) C() <-chan Ticker { return b.ch }
+
+// Send enqueues t according to the policy. Under DropOldest it never blocks.
+func (b *TickBuffer) Send(t Ticker) {
+	if b.policy == Block {
+		b.ch <- t
+		return
+	}
+	for {
+		select {
+		case b.ch <- t:
+			return
+		default:
+			select {
+			case <-b.ch:
+				metricTicksDropped.Inc()
+			default:
+			}
+		}
+	}
+}
+
+// Pump forwards src into the buffer until src is closed, then closes C.
+func (b *TickBuffer) Pump(src <-chan Ticker) {
+	for t := range src {
+		b.Send(t)
+	}
+	close(b.ch)
+}
@@ -0,0 +1,59 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTickBufferDropOldestKeepsLatest(t *testing.T) {
+	b := NewTickBuffer(2, DropOldest)
+	before := testutil.ToFloat64(metricTicksDropped)
+	done := make(chan struct{})
+	go func() { // the consumer is stalled: nothing reads C yet
+		for _, px := range []float64{100, 101, 102, 103, 104} {
+			b.Send(Ticker{Symbol: "BTC-USD", Price: px})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drop-oldest Send blocked on a full buffer")
+	}
+	if got := testutil.ToFloat64(metricTicksDropped) - before; got != 3 {
+		t.Errorf("bot_ticks_dropped_total rose by %v, want 3", got)
+	}
+	for _, want := range []float64{103, 104} {
+		if tk := <-b.C(); tk.Price != want {
+			t.Errorf("buffered tick %v, want %v (the newest survive)", tk.Price, want)
+		}
+	}
+}
+
+func TestTickBufferBlockWaitsForConsumer(t *testing.T) {
+	b := NewTickBuffer(1, Block)
+	before := testutil.ToFloat64(metricTicksDropped)
+	b.Send(Ticker{Symbol: "BTC-USD", Price: 100})
+	sent := make(chan struct{})
+	go func() {
+		b.Send(Ticker{Symbol: "BTC-USD", Price: 101})
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("Send on a full blocking buffer returned before the consumer read")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if tk := <-b.C(); tk.Price != 100 {
+		t.Errorf("first tick %v, want 100", tk.Price)
+	}
+	<-sent
+	if tk := <-b.C(); tk.Price != 101 {
+		t.Errorf("second tick %v, want 101", tk.Price)
+	}
+	if got := testutil.ToFloat64(metricTicksDropped) - before; got != 0 {
+		t.Errorf("blocking buffer dropped %v ticks", got)
+	}
+}
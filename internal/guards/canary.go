@@ -0,0 +1,116 @@
+package guards
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+var (
+	metricCanaryRuns     = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_canary_runs_total", Help: "Paper canary round trips attempted"})
+	metricCanaryFailures = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_canary_failures_total", Help: "Paper canary round trips that did not fill/settle"})
+)
+
+func init() { prometheus.MustRegister(metricCanaryRuns, metricCanaryFailures) }
+
+// canaryUnwindTries is how many times a round trip tries to sell its buy back
+// before leaving the rest to the next MaybeRun.
+const canaryUnwindTries = 3
+
+// Canary is a synthetic end-to-end health check: at a fixed interval it buys a
+// tiny qty through the (safe) exchange, verifies the account position moved, and
+// sells it back. It refuses to exist outside paper mode.
+//
+// Its orders go through PlaceSelfTest where the exchange has it: on a
+// SafeExchange they do not use up the bot's rate limit, dedupe window or daily
+// order budget, and on the paper sim they touch neither its FIFO lots nor its
+// cost model, so the bot's realized PnL and loss cap don't see them.
+type Canary struct {
+	ex     exchange.Exchange
+	symbol string
+	qty    float64
+	every  time.Duration
+	last   time.Time
+	unwind float64 // bought qty whose sell-back failed, retried on every MaybeRun
+}
+
+func NewCanary(mode string, ex exchange.Exchange, symbol string, qty float64, every time.Duration) (*Canary, error) {
+	if mode != "paper" {
+		return nil, errors.New("canary is paper-only")
+	}
+	if qty <= 0 || every <= 0 {
+		return nil, errors.New("canary needs a positive qty and interval")
+	}
+	return &Canary{ex: ex, symbol: symbol, qty: qty, every: every}, nil
+}
+
+// MaybeRun performs a round trip when the interval has elapsed; a non-nil error
+// means the pipeline is broken somewhere between guards and the fill. A buy left
+// open by a failed sell-back is retried first, on every call, until it is sold.
+func (c *Canary) MaybeRun(now time.Time) error {
+	if c == nil {
+		return nil
+	}
+	if c.unwind > 0 {
+		if err := c.sellBack(); err != nil {
+			return err
+		}
+	}
+	if now.Sub(c.last) < c.every {
+		return nil
+	}
+	c.last = now
+	metricCanaryRuns.Inc()
+	if err := c.roundTrip(); err != nil {
+		metricCanaryFailures.Inc()
+		return err
+	}
+	return nil
+}
+
+func (c *Canary) roundTrip() error {
+	before, err := c.ex.Account()
+	if err != nil { return fmt.Errorf("canary: account before: %w", err) }
+	if _, err := c.place(exchange.Buy, c.qty); err != nil {
+		return fmt.Errorf("canary: buy: %w", err)
+	}
+	c.unwind = c.qty
+	after, err := c.ex.Account()
+	if err != nil {
+		if uerr := c.sellBack(); uerr != nil { return uerr }
+		return fmt.Errorf("canary: account after: %w", err)
+	}
+
+	moved := after.Positions[c.symbol].BaseQty - before.Positions[c.symbol].BaseQty
+	if err := c.sellBack(); err != nil {
+		return err
+	}
+	if moved < c.qty*0.999 {
+		return fmt.Errorf("canary: buy of %.8f not reflected in account (moved %.8f)", c.qty, moved)
+	}
+	return nil
+}
+
+// sellBack sells the open canary qty, trying canaryUnwindTries times. When all
+// fail the qty stays pending for the next MaybeRun and the error says so.
+func (c *Canary) sellBack() error {
+	var err error
+	for i := 0; i < canaryUnwindTries; i++ {
+		if _, err = c.place(exchange.Sell, c.unwind); err == nil {
+			c.unwind = 0
+			return nil
+		}
+	}
+	return fmt.Errorf("canary: unwind sell of %.8f %s failed %d times, position left open: %w", c.unwind, c.symbol, canaryUnwindTries, err)
+}
+
+func (c *Canary) place(side exchange.Side, qty float64) (exchange.Order, error) {
+	if st, ok := c.ex.(exchange.SelfTestPlacer); ok {
+		return st.PlaceSelfTest(c.symbol, side, qty)
+	}
+	return c.ex.PlaceMarket(c.symbol, side, qty)
+}
@@ -0,0 +1,155 @@
+package guards
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+	"github.com/chidi150c/coinlila/internal/risk"
+)
+
+// bookVenue is a fakeVenue that also tracks the position of each symbol and,
+// once prices are piped in, cash and equity at those prices; the first
+// failSells sells are refused.
+type bookVenue struct {
+	fakeVenue
+	pos       map[string]float64
+	px        map[string]float64
+	cash      float64
+	failSells int
+}
+
+func (b *bookVenue) Account() (exchange.Account, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	acct := exchange.Account{EquityUSD: b.cash, Positions: map[string]exchange.Position{}}
+	for sym, q := range b.pos {
+		acct.Positions[sym] = exchange.Position{BaseQty: q}
+		acct.EquityUSD += q * b.px[sym]
+	}
+	return acct, nil
+}
+
+func (b *bookVenue) UpdatePrice(symbol string, price float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.px == nil { b.px = map[string]float64{} }
+	b.px[symbol] = price
+}
+
+func (b *bookVenue) PlaceMarket(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if side == exchange.Sell && b.failSells > 0 {
+		b.failSells--
+		return exchange.Order{}, errors.New("sell refused")
+	}
+	if b.pos == nil { b.pos = map[string]float64{} }
+	if side == exchange.Sell { qty = -qty }
+	b.pos[symbol] += qty
+	b.cash -= qty * b.px[symbol]
+	b.ids = append(b.ids, string(side))
+	return exchange.Order{Symbol: symbol, Side: side, Qty: qty}, nil
+}
+
+func (b *bookVenue) PlaceMarketID(symbol string, side exchange.Side, qty float64, _ string) (exchange.Order, error) {
+	return b.PlaceMarket(symbol, side, qty)
+}
+
+func TestCanaryRoundTripSkipsBotAccounting(t *testing.T) {
+	v := &bookVenue{}
+	rs := risk.NewState(10000, 0, time.Now())
+	// a 1/min rate limit and a 1h dedupe window would both block a second buy
+	s := NewSafeExchange(v, rs, risk.Limits{}, 1, 0, time.Millisecond, time.Hour, 3, time.Second, 1)
+	c, err := NewCanary("paper", s, "BTC-USD", 0.001, time.Minute)
+	if err != nil {
+		t.Fatalf("NewCanary: %v", err)
+	}
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(time.Second), now.Add(time.Minute)} {
+		if err := c.MaybeRun(at); err != nil {
+			t.Fatalf("MaybeRun: %v", err)
+		}
+	}
+	if got := strings.Join(v.ids, ","); got != "BUY,SELL,BUY,SELL" {
+		t.Errorf("venue orders = %s, want two round trips (none inside the interval)", got)
+	}
+	if v.pos["BTC-USD"] != 0 {
+		t.Errorf("canary left %v BTC-USD open", v.pos["BTC-USD"])
+	}
+	if rs.OrdersToday() != 0 {
+		t.Errorf("canary spent %d of the daily order budget", rs.OrdersToday())
+	}
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.001); err != nil {
+		t.Errorf("bot order blocked after the canary: %v", err)
+	}
+}
+
+func TestCanaryRetriesFailedUnwind(t *testing.T) {
+	v := &bookVenue{failSells: canaryUnwindTries}
+	c, _ := NewCanary("paper", v, "BTC-USD", 0.001, time.Hour)
+	now := time.Now()
+	if err := c.MaybeRun(now); err == nil || !strings.Contains(err.Error(), "position left open") {
+		t.Fatalf("MaybeRun = %v, want the unwind failure", err)
+	}
+	if v.pos["BTC-USD"] != 0.001 {
+		t.Fatalf("position = %v, want the canary buy still open", v.pos["BTC-USD"])
+	}
+	// the next call retries the sell even though the interval has not elapsed
+	if err := c.MaybeRun(now.Add(time.Second)); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if v.pos["BTC-USD"] != 0 {
+		t.Errorf("position = %v after the retry, want 0", v.pos["BTC-USD"])
+	}
+}
+
+// On the paper sim a canary round trip neither matches the bot's FIFO lots nor
+// pays the sim's costs, so realized PnL and equity are as without it.
+func TestCanaryLeavesSimAccountingAlone(t *testing.T) {
+	v := &bookVenue{cash: 10000}
+	sim := exchange.NewPaperSim(v, v)
+	sim.SetCosts(10, 20)
+	s, _ := newTestExchange(sim, risk.Limits{})
+	c, _ := NewCanary("paper", s, "BTC-USD", 0.5, time.Minute)
+
+	sim.UpdatePrice("BTC-USD", 100)
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 1); err != nil {
+		t.Fatalf("bot buy: %v", err)
+	}
+	sim.UpdatePrice("BTC-USD", 110)
+	before, _ := sim.Account()
+	if err := c.MaybeRun(time.Now()); err != nil {
+		t.Fatalf("MaybeRun: %v", err)
+	}
+	if r := sim.RealizedPnL("BTC-USD"); r != 0 {
+		t.Errorf("canary realized %v against the bot's lot", r)
+	}
+	after, _ := sim.Account()
+	if after.EquityUSD != before.EquityUSD {
+		t.Errorf("canary moved equity from %v to %v", before.EquityUSD, after.EquityUSD)
+	}
+
+	// a canary left open across a price move is still neutral once sold
+	v.failSells = canaryUnwindTries
+	if err := c.MaybeRun(time.Now().Add(time.Hour)); err == nil {
+		t.Fatalf("unwind did not fail")
+	}
+	sim.UpdatePrice("BTC-USD", 120)
+	if err := c.MaybeRun(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("retried unwind: %v", err)
+	}
+	final, _ := sim.Account()
+	if want := before.EquityUSD + 10; final.EquityUSD != want { // the bot's 1 BTC gained 10
+		t.Errorf("equity %v after the canary held through 110→120, want %v", final.EquityUSD, want)
+	}
+
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Sell, 1); err != nil {
+		t.Fatalf("bot sell: %v", err)
+	}
+	if r := sim.RealizedPnL("BTC-USD"); r != 20 {
+		t.Errorf("bot realized %v on 1 BTC from 100 to 120, want 20", r)
+	}
+}
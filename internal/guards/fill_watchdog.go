@@ -0,0 +1,54 @@
+package guards
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricSinceLastFill = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_time_since_last_fill_seconds", Help: "Seconds since the last successful fill"})
+
+func init() { prometheus.MustRegister(metricSinceLastFill) }
+
+// FillWatchdog tells "quiet market" from "silently broken": it tracks the time
+// since the last fill and fires once when it exceeds max while the bot is active.
+type FillWatchdog struct {
+	max      time.Duration
+	now      func() time.Time
+	lastFill time.Time
+	alerted  bool
+	paused   bool
+}
+
+// NewFillWatchdog starts the clock at construction; now may be nil (time.Now).
+func NewFillWatchdog(max time.Duration, now func() time.Time) *FillWatchdog {
+	if now == nil { now = time.Now }
+	return &FillWatchdog{max: max, now: now, lastFill: now()}
+}
+
+// NoteFill resets the quiet period.
+func (w *FillWatchdog) NoteFill() {
+	w.lastFill = w.now()
+	w.alerted = false
+}
+
+// Pause stops the clock while trading is paused or halted. The next Check
+// restarts it, so the time spent inactive never counts toward max.
+func (w *FillWatchdog) Pause() { w.paused = true }
+
+// Check updates the gauge and returns true once per quiet period when the time
+// without a fill exceeds max. Call it only while the bot is active and unpaused.
+func (w *FillWatchdog) Check() bool {
+	if w.paused {
+		w.paused = false
+		w.lastFill = w.now()
+		w.alerted = false
+	}
+	since := w.now().Sub(w.lastFill)
+	metricSinceLastFill.Set(since.Seconds())
+	if w.max <= 0 || w.alerted || since < w.max {
+		return false
+	}
+	w.alerted = true
+	return true
+}
@@ -0,0 +1,44 @@
+package guards
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFillWatchdog(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	w := NewFillWatchdog(10*time.Minute, func() time.Time { return now })
+
+	now = now.Add(9 * time.Minute)
+	if w.Check() {
+		t.Fatal("alerted before max")
+	}
+	now = now.Add(2 * time.Minute)
+	if !w.Check() {
+		t.Fatal("no alert after max without a fill")
+	}
+	if w.Check() {
+		t.Error("alerted twice in one quiet period")
+	}
+
+	w.NoteFill()
+	now = now.Add(5 * time.Minute)
+	if w.Check() {
+		t.Error("alerted 5m after a fill")
+	}
+
+	// an hour paused, then resumed: the clock restarts at the resume
+	w.Pause()
+	now = now.Add(time.Hour)
+	if w.Check() {
+		t.Error("alerted right after a resume")
+	}
+	now = now.Add(9 * time.Minute)
+	if w.Check() {
+		t.Error("time before the pause counted after the resume")
+	}
+	now = now.Add(2 * time.Minute)
+	if !w.Check() {
+		t.Error("no alert for a quiet period after the resume")
+	}
+}
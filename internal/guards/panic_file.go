@@ -0,0 +1,38 @@
+package guards
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricPanicActive = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_panic_file_active", Help: "1 while the panic file halts trading"})
+
+func init() { prometheus.MustRegister(metricPanicActive) }
+
+// PanicFile is a zero-dependency ops kill-switch: while the file at Path exists,
+// the bot must not place new orders. An empty Path disables the check.
+type PanicFile struct {
+	Path   string
+	active bool
+}
+
+func NewPanicFile(path string) *PanicFile { return &PanicFile{Path: path} }
+
+// Check stats the file (cheap, call once per tick) and reports whether trading is
+// halted and whether that state changed since the previous call.
+func (p *PanicFile) Check() (active, changed bool) {
+	if p == nil || p.Path == "" {
+		return false, false
+	}
+	_, err := os.Stat(p.Path)
+	active = err == nil
+	changed = active != p.active
+	p.active = active
+	if active {
+		metricPanicActive.Set(1)
+	} else {
+		metricPanicActive.Set(0)
+	}
+	return active, changed
+}
@@ -0,0 +1,38 @@
+package guards
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPanicFileHaltsWhilePresent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PANIC")
+	p := NewPanicFile(path)
+	if active, changed := p.Check(); active || changed {
+		t.Fatalf("no file: active=%v changed=%v, want neither", active, changed)
+	}
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if active, changed := p.Check(); !active || !changed {
+		t.Fatalf("file created: active=%v changed=%v, want a halt", active, changed)
+	}
+	if active, changed := p.Check(); !active || changed {
+		t.Errorf("file still there: active=%v changed=%v, want halted, unchanged", active, changed)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if active, changed := p.Check(); active || !changed {
+		t.Errorf("file removed: active=%v changed=%v, want a resume", active, changed)
+	}
+}
+
+func TestPanicFileEmptyPathIsOff(t *testing.T) {
+	if active, _ := NewPanicFile("").Check(); active {
+		t.Error("an empty path halted trading")
+	}
+}
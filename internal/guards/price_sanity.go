@@ -0,0 +1,116 @@
+package guards
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricPriceDivergence = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_price_divergence_bp", Help: "Divergence between venue price and the reference source (bp)"})
+
+func init() { prometheus.MustRegister(metricPriceDivergence) }
+
+// PriceSource is an advisory reference price; it is never used for execution.
+type PriceSource interface {
+	Price(symbol string) (float64, error)
+}
+
+// HTTPPriceSource polls a public REST endpoint returning a JSON object.
+// "{symbol}" in URL is replaced by the symbol; Field names the price key,
+// whose value may be a JSON number or a numeric string.
+type HTTPPriceSource struct {
+	URL    string
+	Field  string
+	Client *http.Client
+}
+
+func NewHTTPPriceSource(url, field string) *HTTPPriceSource {
+	if field == "" { field = "price" }
+	return &HTTPPriceSource{URL: url, Field: field, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (h *HTTPPriceSource) Price(symbol string) (float64, error) {
+	resp, err := h.Client.Get(strings.ReplaceAll(h.URL, "{symbol}", symbol))
+	if err != nil { return 0, err }
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("reference price: http %d", resp.StatusCode)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil { return 0, err }
+	switch v := body[h.Field].(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("reference price: field %q missing", h.Field)
+	}
+}
+
+// PriceSanity cross-checks the venue price against a slower reference source and
+// refuses trading when they diverge by more than maxBp. Without a fresh reference
+// the check passes (the source is advisory only).
+type PriceSanity struct {
+	src    PriceSource
+	symbol string
+	maxBp  float64
+	maxAge time.Duration
+
+	mu    sync.Mutex
+	ref   float64
+	refAt time.Time
+}
+
+func NewPriceSanity(src PriceSource, symbol string, maxDivergenceBp float64, maxAge time.Duration) *PriceSanity {
+	return &PriceSanity{src: src, symbol: symbol, maxBp: maxDivergenceBp, maxAge: maxAge}
+}
+
+// Poll fetches the reference price once.
+func (p *PriceSanity) Poll() error {
+	px, err := p.src.Price(p.symbol)
+	if err != nil { return err }
+	p.mu.Lock()
+	p.ref, p.refAt = px, time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// Start polls every interval in the background until the returned stop func is called.
+func (p *PriceSanity) Start(every time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		_ = p.Poll()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				_ = p.Poll()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Check reports whether price agrees with the reference and the divergence in bp.
+func (p *PriceSanity) Check(price float64) (ok bool, divergenceBp float64) {
+	p.mu.Lock()
+	ref, at := p.ref, p.refAt
+	p.mu.Unlock()
+	if ref <= 0 || price <= 0 || (p.maxAge > 0 && time.Since(at) > p.maxAge) {
+		return true, 0
+	}
+	divergenceBp = math.Abs(price-ref) / ref * 10000
+	metricPriceDivergence.Set(divergenceBp)
+	return p.maxBp <= 0 || divergenceBp <= p.maxBp, divergenceBp
+}
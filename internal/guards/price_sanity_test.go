@@ -0,0 +1,39 @@
+package guards
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubPrice struct {
+	px  float64
+	err error
+}
+
+func (s stubPrice) Price(string) (float64, error) { return s.px, s.err }
+
+func TestPriceSanityBlocksDivergence(t *testing.T) {
+	ps := NewPriceSanity(stubPrice{px: 100}, "BTC-USD", 50, 0)
+	if err := ps.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, bp := ps.Check(100.3); !ok || bp < 29.9 || bp > 30.1 {
+		t.Errorf("30bp apart: ok=%v bp=%v, want allowed", ok, bp)
+	}
+	if ok, bp := ps.Check(101); ok || bp < 99.9 || bp > 100.1 {
+		t.Errorf("100bp apart: ok=%v bp=%v, want blocked", ok, bp)
+	}
+	if ok, _ := ps.Check(99.6); !ok {
+		t.Error("40bp below the reference blocked")
+	}
+}
+
+func TestPriceSanityPassesWithoutReference(t *testing.T) {
+	ps := NewPriceSanity(stubPrice{err: errors.New("down")}, "BTC-USD", 50, 0)
+	if err := ps.Poll(); err == nil {
+		t.Fatal("Poll hid the source error")
+	}
+	if ok, _ := ps.Check(200); !ok {
+		t.Error("blocked with no reference price; the source is advisory")
+	}
+}
@@ -1,15 +1,19 @@
 package guards
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"log/slog"
+	"math"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/chidi150c/coinlila/internal/events"
 	"github.com/chidi150c/coinlila/internal/exchange"
 	"github.com/chidi150c/coinlila/internal/risk"
 )
@@ -22,19 +26,41 @@ const (
 	breakerOpen
 )
 
+func (b breakerState) String() string {
+	switch b {
+	case breakerClosed:
+		return "closed"
+	case breakerHalfOpen:
+		return "half_open"
+	case breakerOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
 var (
 	metricOrdersAttempted  = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_attempted_total", Help: "Orders the bot tried to place"})
 	metricOrdersPlaced     = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_placed_total", Help: "Orders successfully handed to exchange"})
 	metricOrdersFailed     = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_failed_total", Help: "Orders that failed after retries"})
 	metricOrdersSuppressed = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_suppressed_total", Help: "Orders blocked by safety layer (rate/idempotency/breaker/cooldown)"})
 	metricBreakerState     = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_breaker_state", Help: "0=closed, 1=half_open, 2=open"})
-	metricRateWindow       = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_orders_in_last_minute", Help: "Orders counted in the current minute window"})
+	metricRateWindow       = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_orders_in_last_minute", Help: "Order tokens currently available in the rate-limit bucket"})
+	metricInFlight         = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_orders_inflight", Help: "Order placements currently in flight"})
+	metricInFlightRejected = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_inflight_rejected_total", Help: "Orders rejected because MAX_INFLIGHT_ORDERS were already in flight"})
+	metricOrdersShadow     = prometheus.NewCounter(prometheus.CounterOpts{Name: "bot_orders_shadow_total", Help: "Orders filled synthetically in shadow mode instead of being sent to the exchange"})
+	metricOrderLatency     = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bot_order_latency_seconds",
+		Help:    "Duration of each venue order call, one observation per attempt (backoff excluded)",
+		Buckets: []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"attempt", "outcome"}) // attempt: first|retry; outcome: ok|transient|permanent
 )
 
 func init() {
 	prometheus.MustRegister(
 		metricOrdersAttempted, metricOrdersPlaced, metricOrdersFailed,
 		metricOrdersSuppressed, metricBreakerState, metricRateWindow,
+		metricInFlight, metricInFlightRejected, metricOrdersShadow,
+		metricOrderLatency,
 	)
 	metricBreakerState.Set(0)
 }
@@ -43,21 +69,36 @@ func init() {
 type SafeExchange struct {
 	inner exchange.Exchange
 	riskS *risk.State
+
+	// Shadow mode: orders are logged and filled here instead of on inner (nil = off)
+	shadow exchange.Exchange
+
+	// Order and breaker events (nil = not published)
+	events *events.Bus
+
+	// Venue-confirmed fill of the latest market order per symbol (see TakeFill)
+	fillMu sync.Mutex
+	fills  map[string]exchange.OrderStatus
 	lim   risk.Limits
 
-	// Rate limiting (simple sliding window)
+	// Rate limiting (token bucket: refills perMinuteCap/60 per second, holds up to burst)
 	rateMu       sync.Mutex
-	orderTimes   []time.Time
+	tokens       float64
+	tokensAt     time.Time
+	burst        int
 	perMinuteCap int
 
 	// Retries
 	maxRetries int
 	backoff    time.Duration
 
-	// Duplicate suppression
-	dupWindow    time.Duration
-	lastOrderKey string
-	lastOrderAt  time.Time
+	// Concurrency cap (nil = unlimited)
+	inflight chan struct{}
+
+	// Duplicate suppression: order key -> when its window expires
+	dupMu     sync.Mutex
+	dupWindow time.Duration
+	recent    map[string]time.Time
 
 	// Circuit breaker
 	bMu        sync.Mutex
@@ -68,6 +109,12 @@ type SafeExchange struct {
 	openedAt   time.Time
 	halfProbes int
 	halfMax    int
+
+	// Prolonged-open escalation
+	openSince   time.Time // start of the current open episode (zero when closed)
+	maxOpen     time.Duration
+	escalations int
+	onProlonged func(openFor time.Duration, level int)
 }
 
 func NewSafeExchange(
@@ -81,13 +128,20 @@ func NewSafeExchange(
 	breakerThreshold int,
 	breakerCooldown time.Duration,
 	breakerHalfOpenProbes int,
+	burst ...int, // optional token-bucket burst size; defaults to perMinuteCap
 ) *SafeExchange {
 	if breakerThreshold < 1 { breakerThreshold = 3 }
 	if breakerHalfOpenProbes < 1 { breakerHalfOpenProbes = 1 }
+	b := perMinuteCap
+	if len(burst) > 0 && burst[0] > 0 { b = burst[0] }
+	metricRateWindow.Set(float64(b))
 	return &SafeExchange{
 		inner:        inner,
 		riskS:        rs,
 		lim:          lim,
+		tokens:       float64(b),
+		tokensAt:     time.Now(),
+		burst:        b,
 		perMinuteCap: perMinuteCap,
 		maxRetries:   maxRetries,
 		backoff:      backoff,
@@ -99,89 +153,389 @@ func NewSafeExchange(
 	}
 }
 
+// SetShadow turns on shadow mode: every order still goes through all the safety
+// checks, but is then logged and filled synthetically on fill (typically a paper
+// engine fed the live prices) instead of being sent to the inner exchange.
+// Account reads follow the shadow book so positions track the synthetic fills;
+// prices keep coming from inner. Call before trading starts.
+func (s *SafeExchange) SetShadow(fill exchange.Exchange) { s.shadow = fill }
+
+// SetEvents publishes placed and failed orders and breaker changes to bus. Call
+// before trading starts.
+func (s *SafeExchange) SetEvents(bus *events.Bus) { s.events = bus }
+
+// publishOrder reports the outcome of an order attempt on the event bus.
+func (s *SafeExchange) publishOrder(symbol string, side exchange.Side, qty float64, err error) {
+	e := events.Event{Kind: events.OrderPlaced, Symbol: symbol, Side: string(side), Qty: qty}
+	if err != nil { e.Kind, e.Detail = events.OrderFailed, err.Error() }
+	s.events.Publish(e)
+}
+
+// venue is where orders go: the shadow fill engine when set, else inner.
+func (s *SafeExchange) venue() exchange.Exchange {
+	if s.shadow != nil {
+		return s.shadow
+	}
+	return s.inner
+}
+
+// placeOn places a market order on the venue, tagged with clientID when the
+// venue accepts client order IDs, and logs and counts it when it is a shadow fill.
+func (s *SafeExchange) placeOn(symbol string, side exchange.Side, qty float64, clientID string) (exchange.Order, error) {
+	place := s.venue().PlaceMarket
+	if cp, ok := s.venue().(exchange.ClientIDPlacer); ok && clientID != "" {
+		place = func(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+			return cp.PlaceMarketID(symbol, side, qty, clientID)
+		}
+	}
+	ord, err := place(symbol, side, qty)
+	if fc, ok := s.venue().(exchange.FillConfirmer); ok && err == nil && clientID != "" {
+		if st, ok := fc.Confirmed(clientID); ok {
+			s.fillMu.Lock()
+			if s.fills == nil { s.fills = map[string]exchange.OrderStatus{} }
+			s.fills[symbol] = st
+			s.fillMu.Unlock()
+		}
+	}
+	if err == nil && s.shadow != nil {
+		metricOrdersShadow.Inc()
+		slog.Info("shadow order", "symbol", symbol, "side", string(side), "qty", qty)
+	}
+	return ord, err
+}
+
+// TakeFill returns (and clears) the venue-confirmed fill of the latest market
+// order on symbol: the quantity that actually filled and its average price. ok
+// is false when the venue does not confirm fills (e.g. paper without depth).
+func (s *SafeExchange) TakeFill(symbol string) (exchange.OrderStatus, bool) {
+	s.fillMu.Lock()
+	defer s.fillMu.Unlock()
+	st, ok := s.fills[symbol]
+	delete(s.fills, symbol)
+	return st, ok
+}
+
+// SetMaxInFlight caps concurrent PlaceMarket calls; excess attempts are rejected.
+// n <= 0 removes the cap. Call before the exchange is shared across goroutines.
+func (s *SafeExchange) SetMaxInFlight(n int) {
+	if n <= 0 {
+		s.inflight = nil
+		return
+	}
+	s.inflight = make(chan struct{}, n)
+}
+
+// SetBreakerEscalation calls fn when the breaker has been open (or probing) for
+// longer than maxOpen, then again at 2x, 4x, ... with an increasing level.
+func (s *SafeExchange) SetBreakerEscalation(maxOpen time.Duration, fn func(openFor time.Duration, level int)) {
+	s.bMu.Lock()
+	defer s.bMu.Unlock()
+	s.maxOpen, s.onProlonged = maxOpen, fn
+}
+
+// BreakerState reports the circuit breaker state: "closed", "half_open" or "open".
+func (s *SafeExchange) BreakerState() string {
+	s.bMu.Lock()
+	defer s.bMu.Unlock()
+	return s.bState.String()
+}
+
+// TimeUntilHalfOpen is how long until an open breaker lets a probe through; 0
+// when the breaker is closed or already half-open.
+func (s *SafeExchange) TimeUntilHalfOpen() time.Duration {
+	s.bMu.Lock()
+	defer s.bMu.Unlock()
+	if s.bState != breakerOpen {
+		return 0
+	}
+	if left := s.cooldown - time.Since(s.openedAt); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// OrdersInWindow is how many recent orders still weigh on the rate limit: the
+// tokens spent and not yet refilled, rounded up (0 when the limit is off).
+func (s *SafeExchange) OrdersInWindow() int {
+	if s.perMinuteCap <= 0 { return 0 }
+	s.rateMu.Lock()
+	defer s.rateMu.Unlock()
+	s.refillLocked(time.Now())
+	return int(math.Ceil(float64(s.burst) - s.tokens - 1e-9))
+}
+
+// CheckBreakerOpen fires the escalation callback when due; call it once per tick.
+// It returns how long the breaker has been out of the closed state.
+func (s *SafeExchange) CheckBreakerOpen(now time.Time) time.Duration {
+	s.bMu.Lock()
+	if s.openSince.IsZero() {
+		s.bMu.Unlock()
+		return 0
+	}
+	openFor := now.Sub(s.openSince)
+	var fire func(time.Duration, int)
+	level := 0
+	if s.maxOpen > 0 && s.onProlonged != nil && openFor >= s.maxOpen<<s.escalations {
+		s.escalations++
+		fire, level = s.onProlonged, s.escalations
+	}
+	s.bMu.Unlock()
+	if fire != nil {
+		fire(openFor, level)
+	}
+	return openFor
+}
+
+// PlaceMarketBypass sends one order straight to the venue, skipping breaker, rate
+// limit and dedupe. It is only meant for a risk-reducing flatten while the breaker
+// has been open too long; it does not retry and does not touch breaker state.
+// A placed flatten still counts against the daily order budget like any real
+// order, so it can use up the last orders of the day; it is never denied by it.
+func (s *SafeExchange) PlaceMarketBypass(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	metricOrdersAttempted.Inc()
+	started := time.Now()
+	ord, err := s.placeOn(symbol, side, qty, newClientOrderID())
+	observeLatency(0, time.Since(started), err)
+	s.publishOrder(symbol, side, qty, err)
+	if err != nil {
+		metricOrdersFailed.Inc()
+		return ord, err
+	}
+	s.rateNote(time.Now())
+	s.riskS.CountOrder(symbol)
+	metricOrdersPlaced.Inc()
+	return ord, nil
+}
+
+// PlaceSelfTest sends a synthetic health-check order (see Canary) straight to
+// the venue. It is not one of the bot's trades: it skips breaker, rate limit and
+// dedupe like PlaceMarketBypass, and spends no rate tokens or daily order budget.
+// A venue that can keep it out of its own trade accounting (the paper sim's
+// lots and costs) is asked to.
+func (s *SafeExchange) PlaceSelfTest(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	if st, ok := s.venue().(exchange.SelfTestPlacer); ok {
+		return st.PlaceSelfTest(symbol, side, qty)
+	}
+	return s.placeOn(symbol, side, qty, "") // no client ID: its fill is not queued for TakeFill
+}
+
 func (s *SafeExchange) BestBidAsk(symbol string) (float64, float64, error) { return s.inner.BestBidAsk(symbol) }
-func (s *SafeExchange) Account() (exchange.Account, error)                  { return s.inner.Account() }
+func (s *SafeExchange) Account() (exchange.Account, error)                  { return s.venue().Account() }
 func (s *SafeExchange) StreamPrices(symbol string, out chan<- exchange.Ticker) (func(), error) {
 	return s.inner.StreamPrices(symbol, out)
 }
 
 func (s *SafeExchange) PlaceMarket(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	var ord exchange.Order
+	okey := s.ordKey(symbol, side, qty)
+	cid := newClientOrderID() // same ID on every retry below, a fresh one per call
+	err := s.submit(symbol, okey, func() (err error) {
+		ord, err = s.placeOn(symbol, side, qty, cid)
+		return err
+	})
+	s.publishOrder(symbol, side, qty, err)
+	return ord, err
+}
+
+// PlaceLimit sends a GTC limit order through the same in-flight, cooldown,
+// breaker, rate-limit and duplicate checks as PlaceMarket.
+func (s *SafeExchange) PlaceLimit(symbol string, side exchange.Side, qty, limitPrice float64) (exchange.LimitOrder, error) {
+	lp, ok := s.venue().(exchange.LimitPlacer)
+	if !ok {
+		return exchange.LimitOrder{}, errors.New("venue does not support limit orders")
+	}
+	var ord exchange.LimitOrder
+	limitPrice = s.lim.Instruments[symbol].RoundPrice(limitPrice, side == exchange.Buy) // venues reject sub-tick prices
+	okey := s.ordKey(symbol, side, qty) + "@" + strconv.FormatFloat(limitPrice, 'f', 8, 64)
+	err := s.submit(symbol, okey, func() (err error) {
+		ord, err = lp.PlaceLimit(symbol, side, qty, limitPrice)
+		return err
+	})
+	s.publishOrder(symbol, side, qty, err)
+	return ord, err
+}
+
+// CancelAll cancels the order venue's open orders on symbol. It is not gated by
+// the breaker or rate limit: cancelling only ever reduces exposure.
+func (s *SafeExchange) CancelAll(symbol string) error {
+	if c, ok := s.venue().(exchange.Canceler); ok {
+		return c.CancelAll(symbol)
+	}
+	return nil
+}
+
+// OpenOrders lists the order venue's resting orders (nil if it has none).
+func (s *SafeExchange) OpenOrders() []exchange.LimitOrder {
+	if l, ok := s.venue().(exchange.OpenOrderLister); ok {
+		return l.OpenOrders()
+	}
+	return nil
+}
+
+// TakeRestingFills collects the resting orders the venue filled since the last
+// call (nil when it fills none on its own).
+func (s *SafeExchange) TakeRestingFills() []exchange.TradeFill {
+	if r, ok := s.venue().(exchange.RestingFiller); ok {
+		return r.TakeRestingFills()
+	}
+	return nil
+}
+
+// submit applies the safety checks, then calls send with retries + backoff.
+func (s *SafeExchange) submit(symbol, okey string, send func() error) error {
 	now := time.Now()
 	metricOrdersAttempted.Inc()
 
+	// Bound concurrent placements
+	if s.inflight != nil {
+		select {
+		case s.inflight <- struct{}{}:
+			metricInFlight.Set(float64(len(s.inflight)))
+			defer func() {
+				<-s.inflight
+				metricInFlight.Set(float64(len(s.inflight)))
+			}()
+		default:
+			metricOrdersSuppressed.Inc()
+			metricInFlightRejected.Inc()
+			return errors.New("too many in-flight orders")
+		}
+	}
+
 	// Cooldown after previous error
 	if !s.riskS.CanAct(now) {
 		metricOrdersSuppressed.Inc()
-		return exchange.Order{}, errors.New("cooldown active after error")
+		return errors.New("cooldown active after error")
 	}
 
 	// Circuit breaker gating
 	if !s.allowBreaker(now) {
 		metricOrdersSuppressed.Inc()
-		return exchange.Order{}, errors.New("circuit breaker open/half-open blocking")
+		return errors.New("circuit breaker open/half-open blocking")
 	}
 
 	// Per-minute rate limit
 	if s.rateExceeded(now) {
+		s.releaseProbe()
 		metricOrdersSuppressed.Inc()
-		return exchange.Order{}, errors.New("rate limit hit")
+		return errors.New("rate limit hit")
 	}
 
 	// Duplicate suppression (idempotency window)
-	okey := s.ordKey(symbol, side, qty)
-	if okey == s.lastOrderKey && now.Sub(s.lastOrderAt) < s.dupWindow {
+	if s.isDuplicate(now, okey) {
+		s.releaseProbe()
 		metricOrdersSuppressed.Inc()
-		return exchange.Order{}, errors.New("duplicate order suppressed")
+		return errors.New("duplicate order suppressed")
 	}
 
 	// Try with retries + backoff
-	var ord exchange.Order
 	var err error
 	for i := 0; i <= s.maxRetries; i++ {
-		ord, err = s.inner.PlaceMarket(symbol, side, qty)
+		started := time.Now()
+		err = send()
+		observeLatency(i, time.Since(started), err)
 		if err == nil {
-			s.noteSuccess(now, okey)
-			return ord, nil
+			s.noteSuccess(now, symbol, okey)
+			return nil
+		}
+		if !exchange.IsTransient(err) {
+			// the venue refused the order itself (e.g. insufficient funds): retrying
+			// won't help and it says nothing about venue health, so leave the breaker be
+			s.releaseProbe()
+			metricOrdersFailed.Inc()
+			return err
 		}
 		time.Sleep(time.Duration(i+1) * s.backoff)
 	}
 	// Final failure
 	s.noteFailure(now)
 	metricOrdersFailed.Inc()
-	return ord, err
+	return err
 }
 
 // ===== Helpers =====
 
+// observeLatency records one venue call of attempt i (0 = first) in the latency
+// histogram, labelled by how it ended.
+func observeLatency(i int, d time.Duration, err error) {
+	attempt, outcome := "first", "ok"
+	if i > 0 { attempt = "retry" }
+	if err != nil {
+		outcome = "permanent"
+		if exchange.IsTransient(err) { outcome = "transient" }
+	}
+	metricOrderLatency.WithLabelValues(attempt, outcome).Observe(d.Seconds())
+}
+
 func (s *SafeExchange) ordKey(symbol string, side exchange.Side, qty float64) string {
 	h := sha256.Sum256([]byte(symbol + string(side) + strconv.FormatFloat(qty, 'f', 8, 64)))
 	return hex.EncodeToString(h[:8])
 }
 
+// newClientOrderID returns a random (version 4) UUID, which is what Coinbase
+// expects for client_oid. Each PlaceMarket call gets its own and reuses it on
+// its retries, so only a resubmission of the same call is recognised as a
+// repeat; two identical orders placed in a row are two orders.
+func newClientOrderID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("client order id: " + err.Error()) // crypto/rand does not fail on supported platforms
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	x := hex.EncodeToString(b[:])
+	return x[0:8] + "-" + x[8:12] + "-" + x[12:16] + "-" + x[16:20] + "-" + x[20:32]
+}
+
+// isDuplicate reports whether okey was placed within the last dupWindow. Keys
+// are remembered independently, so orders on other symbols don't evict it.
+func (s *SafeExchange) isDuplicate(now time.Time, okey string) bool {
+	if s.dupWindow <= 0 { return false }
+	s.dupMu.Lock()
+	defer s.dupMu.Unlock()
+	for k, exp := range s.recent { // prune lazily; the map holds only a window's worth
+		if !now.Before(exp) { delete(s.recent, k) }
+	}
+	_, ok := s.recent[okey]
+	return ok
+}
+
+func (s *SafeExchange) noteOrderKey(now time.Time, okey string) {
+	if s.dupWindow <= 0 { return }
+	s.dupMu.Lock()
+	if s.recent == nil { s.recent = map[string]time.Time{} }
+	s.recent[okey] = now.Add(s.dupWindow)
+	s.dupMu.Unlock()
+}
+
+// rateExceeded reports whether no whole token is available. Tokens are only
+// spent by rateNote, i.e. by orders that actually went out.
 func (s *SafeExchange) rateExceeded(now time.Time) bool {
+	if s.perMinuteCap <= 0 { return false }
 	s.rateMu.Lock()
 	defer s.rateMu.Unlock()
-	oneMin := now.Add(-1 * time.Minute)
-	// keep only recent timestamps
-	j := 0
-	for _, t := range s.orderTimes {
-		if t.After(oneMin) {
-			s.orderTimes[j] = t
-			j++
-		}
-	}
-	s.orderTimes = s.orderTimes[:j]
-	metricRateWindow.Set(float64(len(s.orderTimes)))
-	return s.perMinuteCap > 0 && len(s.orderTimes) >= s.perMinuteCap
+	s.refillLocked(now)
+	return s.tokens < 1
 }
 
 func (s *SafeExchange) rateNote(t time.Time) {
+	if s.perMinuteCap <= 0 { return }
 	s.rateMu.Lock()
-	s.orderTimes = append(s.orderTimes, t)
-	metricRateWindow.Set(float64(len(s.orderTimes)))
+	s.refillLocked(t)
+	if s.tokens--; s.tokens < 0 { s.tokens = 0 }
+	metricRateWindow.Set(s.tokens)
 	s.rateMu.Unlock()
 }
 
+func (s *SafeExchange) refillLocked(now time.Time) {
+	if el := now.Sub(s.tokensAt); el > 0 {
+		s.tokens += el.Seconds() * float64(s.perMinuteCap) / 60
+		if s.tokens > float64(s.burst) { s.tokens = float64(s.burst) }
+		s.tokensAt = now
+	}
+	metricRateWindow.Set(s.tokens)
+}
+
 func (s *SafeExchange) allowBreaker(now time.Time) bool {
 	s.bMu.Lock()
 	defer s.bMu.Unlock()
@@ -192,14 +546,14 @@ func (s *SafeExchange) allowBreaker(now time.Time) bool {
 	case breakerOpen:
 		// move to half-open after cooldown
 		if now.Sub(s.openedAt) >= s.cooldown {
-			s.bState = breakerHalfOpen
-			s.halfProbes = 0
-			metricBreakerState.Set(1)
-			return true // allow first probe
+			s.setBreakerLocked(breakerHalfOpen)
+			s.halfProbes = 1 // the first probe counts against halfMax too
+			return true
 		}
 		return false
 	case breakerHalfOpen:
-		// allow limited probes, increment when we grant one
+		// allow limited probes, increment when we grant one; the slots are
+		// only freed when a probe resolves (noteSuccess/noteFailure)
 		if s.halfProbes < s.halfMax {
 			s.halfProbes++
 			return true
@@ -210,10 +564,37 @@ func (s *SafeExchange) allowBreaker(now time.Time) bool {
 	}
 }
 
-func (s *SafeExchange) noteSuccess(now time.Time, okey string) {
+// releaseProbe gives back a half-open probe slot for an order that was admitted
+// by allowBreaker but then stopped by a later local check, so never reached the
+// venue and will not resolve the probe.
+func (s *SafeExchange) releaseProbe() {
+	s.bMu.Lock()
+	defer s.bMu.Unlock()
+	if s.bState == breakerHalfOpen && s.halfProbes > 0 {
+		s.halfProbes--
+	}
+}
+
+// setBreakerLocked moves the breaker to `to`, updating the gauge and logging the
+// transition through the default (structured) logger. Caller holds bMu.
+func (s *SafeExchange) setBreakerLocked(to breakerState) {
+	from := s.bState
+	s.bState = to
+	metricBreakerState.Set(float64(to))
+	if from != to {
+		slog.Warn("breaker state change", "from", from.String(), "to", to.String(), "fail_streak", s.failStreak)
+		s.events.Publish(events.Event{Kind: events.Breaker, Detail: to.String()})
+	}
+}
+
+// noteSuccess is the one place a placed order is counted against the daily
+// order budget (State.CountOrder), so MaxOrdersPerDay sees every real order.
+func (s *SafeExchange) noteSuccess(now time.Time, symbol, okey string) {
 	// update rate and dup keys
 	s.rateNote(now)
-	s.lastOrderKey, s.lastOrderAt = okey, now
+	s.noteOrderKey(now, okey)
+	s.riskS.NoteSuccess()
+	s.riskS.CountOrder(symbol)
 	metricOrdersPlaced.Inc()
 
 	// breaker transitions
@@ -224,9 +605,9 @@ func (s *SafeExchange) noteSuccess(now time.Time, okey string) {
 		s.failStreak = 0
 	case breakerHalfOpen:
 		// success in half-open -> close
-		s.bState = breakerClosed
 		s.failStreak = 0
-		metricBreakerState.Set(0)
+		s.openSince, s.escalations = time.Time{}, 0
+		s.setBreakerLocked(breakerClosed)
 	case breakerOpen:
 		// shouldn't happen (allowBreaker would block), ignore
 	}
@@ -243,15 +624,14 @@ func (s *SafeExchange) noteFailure(now time.Time) {
 		s.failStreak++
 		if s.failStreak >= s.threshold {
 			s.openedAt = now
-			s.bState = breakerOpen
-			metricBreakerState.Set(2)
+			s.openSince = now
+			s.setBreakerLocked(breakerOpen)
 		}
 	case breakerHalfOpen:
 		// failed probe -> reopen immediately
 		s.openedAt = now
-		s.bState = breakerOpen
 		s.failStreak = s.threshold
-		metricBreakerState.Set(2)
+		s.setBreakerLocked(breakerOpen)
 	case breakerOpen:
 		// already open; keep timer fresh (optional)
 		s.openedAt = now
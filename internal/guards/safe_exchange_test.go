@@ -0,0 +1,317 @@
+package guards
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+	"github.com/chidi150c/coinlila/internal/risk"
+)
+
+// fakeVenue accepts every market order and records the client order IDs it
+// was sent; the first fail calls return err (a transient timeout when nil).
+// With gate set, each order waits for a value on it (or its close).
+type fakeVenue struct {
+	mu   sync.Mutex
+	ids  []string
+	fail int
+	err  error
+	gate chan struct{}
+}
+
+func (f *fakeVenue) BestBidAsk(symbol string) (float64, float64, error) { return 100, 100, nil }
+func (f *fakeVenue) Account() (exchange.Account, error)                  { return exchange.Account{}, nil }
+func (f *fakeVenue) StreamPrices(symbol string, out chan<- exchange.Ticker) (func(), error) {
+	return func() {}, nil
+}
+func (f *fakeVenue) PlaceMarket(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	return f.PlaceMarketID(symbol, side, qty, "")
+}
+func (f *fakeVenue) PlaceMarketID(symbol string, side exchange.Side, qty float64, clientOrderID string) (exchange.Order, error) {
+	if f.gate != nil { <-f.gate }
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ids = append(f.ids, clientOrderID)
+	if f.fail > 0 {
+		f.fail--
+		if f.err != nil { return exchange.Order{}, f.err }
+		return exchange.Order{}, exchange.Transient(errors.New("timeout"))
+	}
+	return exchange.Order{}, nil
+}
+
+func newTestExchange(v exchange.Exchange, lim risk.Limits) (*SafeExchange, *risk.State) {
+	rs := risk.NewState(10000, 0, time.Now())
+	return NewSafeExchange(v, rs, lim, 0, 2, time.Millisecond, 0, 3, time.Second, 1), rs
+}
+
+func TestClientOrderIDFreshPerCallReusedOnRetry(t *testing.T) {
+	v := &fakeVenue{fail: 1}
+	s, _ := newTestExchange(v, risk.Limits{})
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+		t.Fatalf("first order: %v", err)
+	}
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+		t.Fatalf("second order: %v", err)
+	}
+	if len(v.ids) != 3 {
+		t.Fatalf("venue saw %d attempts, want 3 (failed try, retry, second order)", len(v.ids))
+	}
+	if v.ids[0] != v.ids[1] {
+		t.Errorf("retry used a new client order ID: %s then %s", v.ids[0], v.ids[1])
+	}
+	if v.ids[1] == v.ids[2] {
+		t.Errorf("two identical orders share client order ID %s", v.ids[1])
+	}
+}
+
+func TestOrderCapCountsPlacedOrders(t *testing.T) {
+	const n = 3
+	lim := risk.Limits{MaxOrdersPerDay: n, MaxPositionUSD: 1000, MaxOrderNotionalUSD: 100}
+	s, rs := newTestExchange(&fakeVenue{}, lim)
+	for i := 0; i < n; i++ {
+		if dec := risk.DecideBuy(rs, lim, "BTC-USD", 100, 0, 0); dec.Code == risk.DenyOrderCap {
+			t.Fatalf("order %d denied by the cap: %s", i+1, dec.Reason)
+		}
+		if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+			t.Fatalf("order %d: %v", i+1, err)
+		}
+	}
+	if got := rs.OrdersToday(); got != n {
+		t.Fatalf("OrdersToday = %d after %d placements", got, n)
+	}
+	if dec := risk.DecideBuy(rs, lim, "BTC-USD", 100, 0, 0); dec.Allow || dec.Code != risk.DenyOrderCap {
+		t.Errorf("buy %d = %+v, want denied with %s", n+1, dec, risk.DenyOrderCap)
+	}
+	if dec := risk.DecideSignalSell(rs, lim, "BTC-USD", 100, 0.03, 100); dec.Allow || dec.Code != risk.DenyOrderCap {
+		t.Errorf("signal sell %d = %+v, want denied with %s", n+1, dec, risk.DenyOrderCap)
+	}
+	if dec := risk.DecideSell(rs, lim, "BTC-USD", 100, 0.03, 100); !dec.Allow {
+		t.Errorf("protective sell denied past the cap: %+v", dec)
+	}
+}
+
+func TestDedupeInterleavedSymbols(t *testing.T) {
+	v := &fakeVenue{}
+	rs := risk.NewState(10000, 0, time.Now())
+	s := NewSafeExchange(v, rs, risk.Limits{}, 0, 0, time.Millisecond, time.Minute, 3, time.Second, 1)
+	for _, sym := range []string{"BTC-USD", "ETH-USD"} {
+		if _, err := s.PlaceMarket(sym, exchange.Buy, 0.01); err != nil {
+			t.Fatalf("first %s order: %v", sym, err)
+		}
+	}
+	// each repeat is caught although the other symbol was placed after it
+	for _, sym := range []string{"BTC-USD", "ETH-USD"} {
+		if _, err := s.PlaceMarket(sym, exchange.Buy, 0.01); err == nil {
+			t.Errorf("repeated %s order was not suppressed", sym)
+		}
+	}
+	if len(v.ids) != 2 {
+		t.Errorf("venue saw %d orders, want 2", len(v.ids))
+	}
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Sell, 0.01); err != nil {
+		t.Errorf("opposite side suppressed as a duplicate: %v", err)
+	}
+	if s.isDuplicate(time.Now().Add(time.Minute+time.Second), s.ordKey("ETH-USD", exchange.Buy, 0.01)) {
+		t.Errorf("key still remembered after the window")
+	}
+}
+
+func TestPermanentErrorsLeaveBreakerClosed(t *testing.T) {
+	v := &fakeVenue{fail: 10, err: exchange.Permanent(errors.New("insufficient funds"))}
+	s, rs := newTestExchange(v, risk.Limits{})
+	rs.SetErrorCooldown(time.Minute, 0) // a counted error would block the next order
+	for i := 0; i < 10; i++ {
+		if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err == nil {
+			t.Fatalf("order %d: rejection not returned", i+1)
+		}
+	}
+	if st := s.BreakerState(); st != "closed" {
+		t.Errorf("breaker %s after 10 rejections, want closed", st)
+	}
+	if len(v.ids) != 10 {
+		t.Errorf("venue saw %d attempts, want 10 (rejections are not retried)", len(v.ids))
+	}
+	if !rs.CanAct(time.Now()) {
+		t.Errorf("rejections started the error cooldown")
+	}
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+		t.Errorf("order after the rejections: %v", err)
+	}
+
+	// the same number of outages does open it
+	v = &fakeVenue{fail: 100}
+	s, _ = newTestExchange(v, risk.Limits{})
+	for i := 0; i < 3; i++ { s.PlaceMarket("BTC-USD", exchange.Buy, 0.01) }
+	if st := s.BreakerState(); st != "open" {
+		t.Errorf("breaker %s after 3 failed orders, want open", st)
+	}
+}
+
+func TestBreakerAccessorsAcrossTransitions(t *testing.T) {
+	v := &fakeVenue{}
+	rs := risk.NewState(10000, 0, time.Now())
+	s := NewSafeExchange(v, rs, risk.Limits{}, 10, 0, time.Millisecond, 0, 3, 50*time.Millisecond, 1)
+	if s.BreakerState() != "closed" || s.TimeUntilHalfOpen() != 0 || s.OrdersInWindow() != 0 {
+		t.Fatalf("fresh: %s, half-open in %v, %d in window", s.BreakerState(), s.TimeUntilHalfOpen(), s.OrdersInWindow())
+	}
+	for i := 0; i < 2; i++ { s.PlaceMarket("BTC-USD", exchange.Buy, 0.01) }
+	if n := s.OrdersInWindow(); n != 2 {
+		t.Errorf("OrdersInWindow = %d after 2 orders, want 2", n)
+	}
+
+	v.fail = 3
+	for i := 0; i < 3; i++ { s.PlaceMarket("BTC-USD", exchange.Buy, 0.01) }
+	if st, left := s.BreakerState(), s.TimeUntilHalfOpen(); st != "open" || left <= 0 || left > 50*time.Millisecond {
+		t.Fatalf("after 3 failures: %s, half-open in %v; want open within 50ms", st, left)
+	}
+	if n := s.OrdersInWindow(); n != 2 {
+		t.Errorf("OrdersInWindow = %d, failed orders must not count", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if st, left := s.BreakerState(), s.TimeUntilHalfOpen(); st != "open" || left != 0 {
+		t.Errorf("cooldown over: %s, half-open in %v; want open, 0", st, left)
+	}
+	// the next order is the half-open probe; hold it at the venue to look
+	v.gate = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.PlaceMarket("BTC-USD", exchange.Buy, 0.01)
+		close(done)
+	}()
+	for deadline := time.Now().Add(time.Second); s.BreakerState() != "half_open"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("breaker never went half-open (state %s)", s.BreakerState())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if left := s.TimeUntilHalfOpen(); left != 0 {
+		t.Errorf("half-open: TimeUntilHalfOpen = %v, want 0", left)
+	}
+	close(v.gate)
+	<-done
+	if st := s.BreakerState(); st != "closed" {
+		t.Errorf("after a good probe: %s, want closed", st)
+	}
+}
+
+func TestMaxInFlightRejectsExcessPlacements(t *testing.T) {
+	v := &fakeVenue{gate: make(chan struct{})}
+	s, _ := newTestExchange(v, risk.Limits{})
+	s.SetMaxInFlight(2)
+
+	var wg sync.WaitGroup
+	for _, sym := range []string{"BTC-USD", "ETH-USD"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.PlaceMarket(sym, exchange.Buy, 0.01); err != nil {
+				t.Errorf("held %s order: %v", sym, err)
+			}
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); len(s.inflight) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d orders in flight, want 2 held at the venue", len(s.inflight))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.PlaceMarket("SOL-USD", exchange.Buy, 0.01); err == nil {
+		t.Error("third concurrent order admitted past a cap of 2")
+	}
+
+	close(v.gate)
+	wg.Wait()
+	if _, err := s.PlaceMarket("SOL-USD", exchange.Buy, 0.01); err != nil {
+		t.Errorf("order after the others finished: %v", err)
+	}
+	if len(v.ids) != 3 {
+		t.Errorf("venue saw %d orders, want 3", len(v.ids))
+	}
+}
+
+func TestBreakerEscalatesWhileOpenTooLong(t *testing.T) {
+	v := &fakeVenue{fail: 3}
+	rs := risk.NewState(10000, 0, time.Now())
+	s := NewSafeExchange(v, rs, risk.Limits{}, 0, 0, time.Millisecond, 0, 3, 10*time.Millisecond, 1)
+	type call struct {
+		openFor time.Duration
+		level   int
+	}
+	var calls []call
+	s.SetBreakerEscalation(time.Minute, func(openFor time.Duration, level int) { calls = append(calls, call{openFor, level}) })
+
+	for i := 0; i < 3; i++ { s.PlaceMarket("BTC-USD", exchange.Buy, 0.01) }
+	opened := time.Now() // the breaker opened just before this
+	// the tick clock is passed in, so an hour-long outage takes no time here
+	for _, at := range []time.Duration{30 * time.Second, 59 * time.Second, 61 * time.Second, 90 * time.Second,
+		2*time.Minute + time.Second, 3 * time.Minute, 4*time.Minute + time.Second} {
+		s.CheckBreakerOpen(opened.Add(at))
+	}
+	if len(calls) != 3 {
+		t.Fatalf("escalations %+v, want 3 (past 1m, 2m and 4m)", calls)
+	}
+	for i, c := range calls {
+		if c.level != i+1 || c.openFor < time.Minute<<i {
+			t.Errorf("escalation %d = %+v, want level %d after %v", i+1, c, i+1, time.Minute<<i)
+		}
+	}
+
+	// a good probe closes the breaker and ends the episode
+	time.Sleep(20 * time.Millisecond)
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if openFor := s.CheckBreakerOpen(opened.Add(time.Hour)); openFor != 0 || len(calls) != 3 {
+		t.Errorf("after closing: open for %v, %d escalations; want 0 and no new one", openFor, len(calls))
+	}
+}
+
+func TestHalfOpenAdmitsExactlyHalfMaxProbes(t *testing.T) {
+	v := &fakeVenue{fail: 3}
+	rs := risk.NewState(10000, 0, time.Now())
+	s := NewSafeExchange(v, rs, risk.Limits{}, 0, 0, time.Millisecond, 0, 3, 10*time.Millisecond, 2)
+	for i := 0; i < 3; i++ { s.PlaceMarket("BTC-USD", exchange.Buy, 0.01) }
+	if st := s.BreakerState(); st != "open" {
+		t.Fatalf("breaker %s after 3 failures, want open", st)
+	}
+	time.Sleep(20 * time.Millisecond) // wait out the cooldown
+
+	// hold the probes at the venue so none resolves while the next one asks
+	v.gate = make(chan struct{})
+	probes := func() int {
+		s.bMu.Lock()
+		defer s.bMu.Unlock()
+		return s.halfProbes
+	}
+	var wg sync.WaitGroup
+	for i := 1; i <= 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err != nil {
+				t.Errorf("probe: %v", err)
+			}
+		}()
+		for deadline := time.Now().Add(time.Second); probes() < i; {
+			if time.Now().After(deadline) {
+				t.Fatalf("probe %d never admitted (state %s)", i, s.BreakerState())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if _, err := s.PlaceMarket("BTC-USD", exchange.Buy, 0.01); err == nil {
+		t.Error("a third probe was admitted with halfMax 2")
+	}
+	close(v.gate)
+	wg.Wait()
+	if st := s.BreakerState(); st != "closed" {
+		t.Errorf("breaker %s after good probes, want closed", st)
+	}
+	if len(v.ids) != 5 {
+		t.Errorf("venue saw %d orders, want 5 (3 failures, 2 probes)", len(v.ids))
+	}
+}
@@ -0,0 +1,151 @@
+package guards
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+// Warmup routes all orders to a paper engine for a warm-up period (a duration or
+// a number of trades, whichever comes first) and only promotes execution to the
+// live exchange when the paper run clears a sanity bar: no placement errors and
+// a realized PnL of at least MinPnLUSD. Otherwise it stays in paper for good.
+type Warmup struct {
+	paper exchange.Exchange
+	live  exchange.Exchange
+
+	until     time.Time
+	maxTrades int
+	MinPnLUSD float64
+
+	mu      sync.Mutex
+	active  exchange.Exchange
+	trades  int
+	pnl     float64
+	errs    int
+	decided bool
+}
+
+func NewWarmup(paper, live exchange.Exchange, dur time.Duration, trades int, now time.Time) *Warmup {
+	return &Warmup{paper: paper, live: live, until: now.Add(dur), maxTrades: trades, active: paper}
+}
+
+// Live reports whether execution has been promoted to the live exchange.
+func (w *Warmup) Live() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active == w.live
+}
+
+// NoteTrade records the realized PnL of a completed (closing) warm-up trade.
+func (w *Warmup) NoteTrade(realizedUSD float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.decided {
+		w.trades++
+		w.pnl += realizedUSD
+	}
+}
+
+// Evaluate decides once the warm-up is over. done is false while still warming
+// up; afterwards promoted says whether execution switched to live, and reason
+// explains a refusal.
+func (w *Warmup) Evaluate(now time.Time) (done, promoted bool, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.decided {
+		return false, false, ""
+	}
+	if now.Before(w.until) && (w.maxTrades <= 0 || w.trades < w.maxTrades) {
+		return false, false, ""
+	}
+	w.decided = true
+	switch {
+	case w.errs > 0:
+		reason = fmt.Sprintf("%d order errors during paper warm-up", w.errs)
+	case w.trades == 0:
+		reason = "no completed trades during paper warm-up"
+	case w.pnl < w.MinPnLUSD:
+		reason = fmt.Sprintf("paper warm-up pnl %.2f below %.2f over %d trades", w.pnl, w.MinPnLUSD, w.trades)
+	default:
+		w.active = w.live
+		return true, true, ""
+	}
+	return true, false, reason
+}
+
+func (w *Warmup) current() exchange.Exchange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active
+}
+
+func (w *Warmup) BestBidAsk(symbol string) (float64, float64, error) { return w.current().BestBidAsk(symbol) }
+func (w *Warmup) Account() (exchange.Account, error)                  { return w.current().Account() }
+func (w *Warmup) StreamPrices(symbol string, out chan<- exchange.Ticker) (func(), error) {
+	return w.live.StreamPrices(symbol, out)
+}
+
+func (w *Warmup) PlaceMarket(symbol string, side exchange.Side, qty float64) (exchange.Order, error) {
+	ex := w.current()
+	ord, err := ex.PlaceMarket(symbol, side, qty)
+	if err != nil && ex == w.paper {
+		w.mu.Lock()
+		w.errs++
+		w.mu.Unlock()
+	}
+	return ord, err
+}
+
+// PlaceMarketID forwards a client-ID-tagged order to the current venue, or
+// places it untagged when the venue does not take client order IDs.
+func (w *Warmup) PlaceMarketID(symbol string, side exchange.Side, qty float64, clientOrderID string) (exchange.Order, error) {
+	ex := w.current()
+	cp, ok := ex.(exchange.ClientIDPlacer)
+	if !ok {
+		return w.PlaceMarket(symbol, side, qty)
+	}
+	ord, err := cp.PlaceMarketID(symbol, side, qty, clientOrderID)
+	if err != nil && ex == w.paper {
+		w.mu.Lock()
+		w.errs++
+		w.mu.Unlock()
+	}
+	return ord, err
+}
+
+// Confirmed forwards fill confirmation to the current venue, if it supports it.
+func (w *Warmup) Confirmed(clientOrderID string) (exchange.OrderStatus, bool) {
+	if fc, ok := w.current().(exchange.FillConfirmer); ok {
+		return fc.Confirmed(clientOrderID)
+	}
+	return exchange.OrderStatus{}, false
+}
+
+// PlaceLimit forwards to the current venue when it supports limit orders.
+func (w *Warmup) PlaceLimit(symbol string, side exchange.Side, qty, limitPrice float64) (exchange.LimitOrder, error) {
+	lp, ok := w.current().(exchange.LimitPlacer)
+	if !ok {
+		return exchange.LimitOrder{}, errors.New("venue does not support limit orders")
+	}
+	return lp.PlaceLimit(symbol, side, qty, limitPrice)
+}
+
+// TakeRestingFills collects resting fills from the current venue, if it has any.
+func (w *Warmup) TakeRestingFills() []exchange.TradeFill {
+	if r, ok := w.current().(exchange.RestingFiller); ok {
+		return r.TakeRestingFills()
+	}
+	return nil
+}
+
+// CancelAll cancels open orders on the current venue, if it supports it.
+func (w *Warmup) CancelAll(symbol string) error {
+	if c, ok := w.current().(exchange.Canceler); ok {
+		return c.CancelAll(symbol)
+	}
+	return nil
+}
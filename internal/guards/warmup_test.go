@@ -0,0 +1,41 @@
+package guards
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+func TestWarmupStaysInPaperWhenItFails(t *testing.T) {
+	start := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name      string
+		paperFail int
+		pnl       float64
+		promoted  bool
+		reason    string
+	}{
+		{"order error", 1, 10, false, "order errors"},
+		{"losing run", 0, -5, false, "below"},
+		{"clean run", 0, 10, true, ""},
+	} {
+		paper, live := &fakeVenue{fail: c.paperFail}, &fakeVenue{}
+		w := NewWarmup(paper, live, time.Hour, 0, start)
+		w.PlaceMarket("BTC-USD", exchange.Buy, 0.01)
+		w.NoteTrade(c.pnl)
+		if done, _, _ := w.Evaluate(start.Add(30 * time.Minute)); done {
+			t.Fatalf("%s: decided before the warm-up ended", c.name)
+		}
+		done, promoted, reason := w.Evaluate(start.Add(time.Hour))
+		if !done || promoted != c.promoted || !strings.Contains(reason, c.reason) {
+			t.Errorf("%s: done=%v promoted=%v reason=%q, want promoted=%v with %q",
+				c.name, done, promoted, reason, c.promoted, c.reason)
+		}
+		w.PlaceMarket("BTC-USD", exchange.Buy, 0.01)
+		if w.Live() != c.promoted || (len(live.ids) == 1) != c.promoted {
+			t.Errorf("%s: live=%v and the live venue saw %d orders after the decision", c.name, w.Live(), len(live.ids))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var csvHeader = []string{"symbol", "qty", "avg_entry", "realized_pnl_usd"}
+
+// ExportCSV writes one row per symbol (quantity, average entry, realized PnL).
+func (l *Ledger) ExportCSV(w io.Writer) error {
+	l.mu.Lock()
+	rows := make([]Position, 0, len(l.pos))
+	for _, p := range l.pos {
+		rows = append(rows, *p)
+	}
+	l.mu.Unlock()
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Symbol < rows[j].Symbol })
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil { return err }
+	for _, p := range rows {
+		rec := []string{
+			p.Symbol,
+			strconv.FormatFloat(p.Qty, 'f', -1, 64),
+			strconv.FormatFloat(p.AvgEntry, 'f', -1, 64),
+			strconv.FormatFloat(p.RealizedPnLUSD, 'f', -1, 64),
+		}
+		if err := cw.Write(rec); err != nil { return err }
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV restores positions written by ExportCSV. All rows are validated first;
+// on any malformed row nothing is applied. Imported symbols replace existing entries.
+func (l *Ledger) ImportCSV(r io.Reader) error {
+	recs, err := csv.NewReader(r).ReadAll()
+	if err != nil { return err }
+	if len(recs) == 0 || strings.Join(recs[0], ",") != strings.Join(csvHeader, ",") {
+		return fmt.Errorf("ledger csv: header must be %s", strings.Join(csvHeader, ","))
+	}
+
+	parsed := make([]Position, 0, len(recs)-1)
+	seen := map[string]bool{}
+	for i, rec := range recs[1:] {
+		line := i + 2
+		if len(rec) != len(csvHeader) {
+			return fmt.Errorf("ledger csv line %d: want %d fields, got %d", line, len(csvHeader), len(rec))
+		}
+		sym := strings.TrimSpace(rec[0])
+		if sym == "" || seen[sym] {
+			return fmt.Errorf("ledger csv line %d: empty or duplicate symbol %q", line, sym)
+		}
+		seen[sym] = true
+		var vals [3]float64
+		for j := range vals {
+			v, err := strconv.ParseFloat(strings.TrimSpace(rec[j+1]), 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("ledger csv line %d: bad %s %q", line, csvHeader[j+1], rec[j+1])
+			}
+			vals[j] = v
+		}
+		if vals[0] < 0 || vals[1] < 0 || (vals[0] > 0 && vals[1] == 0) {
+			return fmt.Errorf("ledger csv line %d: qty/avg_entry must be non-negative and set together", line)
+		}
+		parsed = append(parsed, Position{Symbol: sym, Qty: vals[0], AvgEntry: vals[1], RealizedPnLUSD: vals[2], PeakQty: vals[0]})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range parsed {
+		p := parsed[i]
+		l.pos[p.Symbol] = &p
+	}
+	l.saveLocked()
+	return nil
+}
@@ -0,0 +1,44 @@
+package ledger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	src := New("")
+	src.ApplyFill(Fill{ID: "1", Symbol: "BTC-USD", Side: exchange.Buy, Qty: 0.5, Price: 40000})
+	src.ApplyFill(Fill{ID: "2", Symbol: "BTC-USD", Side: exchange.Buy, Qty: 0.25, Price: 43000})
+	src.ApplyFill(Fill{ID: "3", Symbol: "ETH-USD", Side: exchange.Buy, Qty: 2, Price: 2000})
+	src.ApplyFill(Fill{ID: "4", Symbol: "ETH-USD", Side: exchange.Sell, Qty: 2, Price: 2100})
+
+	var buf bytes.Buffer
+	if err := src.ExportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := New("")
+	if err := dst.ImportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range []string{"BTC-USD", "ETH-USD"} {
+		want, got := src.Position(sym), dst.Position(sym)
+		if got.Qty != want.Qty || got.AvgEntry != want.AvgEntry || got.RealizedPnLUSD != want.RealizedPnLUSD {
+			t.Errorf("%s after round trip = %+v, want %+v", sym, got, want)
+		}
+	}
+}
+
+func TestImportCSVRejectsBadRowWithoutApplying(t *testing.T) {
+	l := New("")
+	l.ApplyFill(Fill{Symbol: "BTC-USD", Side: exchange.Buy, Qty: 1, Price: 100})
+	in := "symbol,qty,avg_entry,realized_pnl_usd\nBTC-USD,2,90,0\nETH-USD,x,1,0\n"
+	if err := l.ImportCSV(strings.NewReader(in)); err == nil {
+		t.Fatal("malformed row accepted")
+	}
+	if p := l.Position("BTC-USD"); p.Qty != 1 || p.AvgEntry != 100 {
+		t.Errorf("a rejected import changed BTC-USD: %+v", p)
+	}
+}
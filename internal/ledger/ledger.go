@@ -0,0 +1,232 @@
+// Package ledger keeps the bot's own per-symbol position book (quantity, average
+// entry, realized PnL) so its trading memory survives restarts.
+package ledger
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+	"github.com/chidi150c/coinlila/internal/util"
+)
+
+// Position is the bot's view of one symbol.
+type Position struct {
+	Symbol         string  `json:"symbol"`
+	Qty            float64 `json:"qty"`
+	AvgEntry       float64 `json:"avg_entry"`
+	RealizedPnLUSD float64 `json:"realized_pnl_usd"`
+
+	// Take-profit ladder progress for the currently open position
+	PeakQty   float64 `json:"peak_qty"`             // largest size held since the position was opened
+	TPFilled  int     `json:"tp_filled"`            // ladder levels already taken
+	TPPartial float64 `json:"tp_partial,omitempty"` // qty sold toward the next level when its order was cut short
+
+	LastTPExitAt time.Time `json:"last_tp_exit_at,omitempty"` // last take-profit-triggered sell
+}
+
+// Fill is an executed trade applied to the ledger. ID is the venue fill/trade ID
+// when known; fills with an ID already seen are ignored.
+type Fill struct {
+	ID     string
+	Symbol string
+	Side   exchange.Side
+	Qty    float64
+	Price  float64
+}
+
+// maxSeenFills bounds the remembered fill IDs (oldest are forgotten first).
+const maxSeenFills = 2000
+
+// Ledger is a mutex-guarded position book persisted as JSON at Path.
+type Ledger struct {
+	mu   sync.Mutex
+	Path string
+	pos  map[string]*Position
+
+	seen     map[string]struct{} // fill IDs already applied
+	seenList []string            // same IDs in arrival order, for eviction
+}
+
+// fileState is the on-disk layout.
+type fileState struct {
+	Positions []Position `json:"positions"`
+	SeenFills []string   `json:"seen_fills,omitempty"`
+}
+
+// New returns a ledger backed by path, loading any existing state (best-effort).
+func New(path string) *Ledger {
+	l := &Ledger{Path: path, pos: map[string]*Position{}, seen: map[string]struct{}{}}
+	if path == "" {
+		return l
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l
+	}
+	var st fileState
+	if json.Unmarshal(b, &st) != nil {
+		// older files were a bare position array
+		st = fileState{}
+		_ = json.Unmarshal(b, &st.Positions)
+	}
+	for i := range st.Positions {
+		p := st.Positions[i]
+		l.pos[p.Symbol] = &p
+	}
+	for _, id := range st.SeenFills {
+		l.markSeen(id)
+	}
+	return l
+}
+
+// Position returns a copy of the symbol's position (zero value when flat/unknown).
+func (l *Ledger) Position(symbol string) Position {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p, ok := l.pos[symbol]; ok {
+		return *p
+	}
+	return Position{Symbol: symbol}
+}
+
+// MarkedValues marks every open position at prices (symbol -> price) and returns
+// the per-symbol USD values and their total. Symbols without a price are skipped.
+func (l *Ledger) MarkedValues(prices map[string]float64) (map[string]float64, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]float64, len(l.pos))
+	var total float64
+	for sym, p := range l.pos {
+		if px, ok := prices[sym]; ok && p.Qty > 0 {
+			out[sym] = p.Qty * px
+			total += out[sym]
+		}
+	}
+	return out, total
+}
+
+// ApplyFill updates quantity/average entry and returns the PnL realized by this fill.
+// Sells are clamped to the held quantity (spot, no shorting). A fill whose ID was
+// already applied (e.g. seen in the order response and again in a later poll) is
+// ignored and realizes nothing.
+func (l *Ledger) ApplyFill(f Fill) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f.ID != "" {
+		if _, dup := l.seen[f.ID]; dup {
+			return 0
+		}
+		l.markSeen(f.ID)
+	}
+	p := l.get(f.Symbol)
+
+	var realized float64
+	switch f.Side {
+	case exchange.Buy:
+		if p.Qty <= 0 {
+			p.PeakQty, p.TPFilled, p.TPPartial = 0, 0, 0
+		}
+		newQty := p.Qty + f.Qty
+		if newQty > 0 {
+			p.AvgEntry = (p.AvgEntry*p.Qty + f.Price*f.Qty) / newQty
+		}
+		p.Qty = newQty
+		if p.Qty > p.PeakQty {
+			p.PeakQty = p.Qty
+		}
+	case exchange.Sell:
+		q := f.Qty
+		if q > p.Qty {
+			q = p.Qty
+		}
+		realized = (f.Price - p.AvgEntry) * q
+		p.RealizedPnLUSD += realized
+		p.Qty -= q
+		if p.Qty <= 1e-12 {
+			p.Qty, p.AvgEntry, p.PeakQty, p.TPFilled, p.TPPartial = 0, 0, 0, 0, 0
+		}
+	}
+	l.saveLocked()
+	return realized
+}
+
+// MarkTPFilled records that the next take-profit ladder level has been taken at `at`.
+func (l *Ledger) MarkTPFilled(symbol string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(symbol)
+	if p.Qty > 0 {
+		p.TPFilled++
+	}
+	p.TPPartial = 0
+	p.LastTPExitAt = at
+	l.saveLocked()
+}
+
+// NoteTPPartial records qty sold toward the next take-profit level without
+// completing it (e.g. the order was capped), so the level stays pending for
+// the rest of its tranche.
+func (l *Ledger) NoteTPPartial(symbol string, qty float64, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(symbol)
+	if p.Qty > 0 {
+		p.TPPartial += qty
+	}
+	p.LastTPExitAt = at
+	l.saveLocked()
+}
+
+// Reset forgets all positions (e.g. after a paper warm-up before going live).
+func (l *Ledger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pos = map[string]*Position{}
+	l.saveLocked()
+}
+
+// ===== Helpers =====
+
+func (l *Ledger) get(symbol string) *Position {
+	p, ok := l.pos[symbol]
+	if !ok {
+		p = &Position{Symbol: symbol}
+		l.pos[symbol] = p
+	}
+	return p
+}
+
+// Seen reports whether a fill with this ID has already been applied.
+func (l *Ledger) Seen(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.seen[id]
+	return ok
+}
+
+func (l *Ledger) markSeen(id string) {
+	l.seen[id] = struct{}{}
+	l.seenList = append(l.seenList, id)
+	if len(l.seenList) > maxSeenFills {
+		delete(l.seen, l.seenList[0])
+		l.seenList = l.seenList[1:]
+	}
+}
+
+func (l *Ledger) saveLocked() {
+	if l.Path == "" {
+		return
+	}
+	st := fileState{Positions: make([]Position, 0, len(l.pos)), SeenFills: l.seenList}
+	for _, p := range l.pos {
+		st.Positions = append(st.Positions, *p)
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = util.WriteFileAtomic(l.Path, b, 0o600) // best-effort
+}
@@ -0,0 +1,37 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+func TestApplyFillIgnoresRepeatedID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := New(path)
+	buy := Fill{ID: "trade-1", Symbol: "BTC-USD", Side: exchange.Buy, Qty: 1, Price: 100}
+	l.ApplyFill(buy)
+	l.ApplyFill(buy) // seen in the order response and again in a fill poll
+	if p := l.Position("BTC-USD"); p.Qty != 1 || p.AvgEntry != 100 {
+		t.Fatalf("after a repeated buy: %+v, want 1 @ 100", p)
+	}
+
+	sell := Fill{ID: "trade-2", Symbol: "BTC-USD", Side: exchange.Sell, Qty: 0.5, Price: 110}
+	if pnl := l.ApplyFill(sell); pnl != 5 {
+		t.Errorf("sell realized %v, want 5", pnl)
+	}
+	if pnl := l.ApplyFill(sell); pnl != 0 {
+		t.Errorf("repeated sell realized %v, want 0", pnl)
+	}
+	if p := l.Position("BTC-USD"); p.Qty != 0.5 || p.RealizedPnLUSD != 5 {
+		t.Errorf("after a repeated sell: %+v, want 0.5 left and 5 realized", p)
+	}
+
+	// the seen IDs are persisted, so a restart doesn't apply them again either
+	restarted := New(path)
+	restarted.ApplyFill(sell)
+	if p := restarted.Position("BTC-USD"); p.Qty != 0.5 {
+		t.Errorf("after a restart: %+v, want the repeated sell ignored", p)
+	}
+}
@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"math"
+
+	"github.com/chidi150c/coinlila/internal/exchange"
+)
+
+// Reconcile makes symbol's book quantity match venueQty, the quantity the venue
+// actually holds, and returns the position as it was before. A larger venue
+// quantity is taken in as a buy at entryPrice; a smaller one is trimmed without
+// realizing PnL, since the exit price is unknown. Differences within tol are
+// left alone.
+func (l *Ledger) Reconcile(symbol string, venueQty, entryPrice, tol float64) (Position, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.get(symbol)
+	before := *p
+	if math.Abs(venueQty-p.Qty) <= tol {
+		return before, false
+	}
+	switch {
+	case venueQty <= tol:
+		p.Qty, p.AvgEntry, p.PeakQty, p.TPFilled, p.TPPartial = 0, 0, 0, 0, 0
+	case venueQty > p.Qty:
+		p.AvgEntry = (p.AvgEntry*p.Qty + entryPrice*(venueQty-p.Qty)) / venueQty
+		p.Qty = venueQty
+	default:
+		p.Qty = venueQty
+	}
+	if p.Qty > p.PeakQty { p.PeakQty = p.Qty }
+	l.saveLocked()
+	return before, true
+}
+
+// EntryFromFills estimates the average entry of a position from the venue's
+// fills (newest first) by replaying them oldest first with the same average-cost
+// rule as ApplyFill. It returns 0 when the replay ends flat, i.e. the fills
+// don't explain a holding.
+func EntryFromFills(fills []exchange.TradeFill) float64 {
+	var qty, avg float64
+	for i := len(fills) - 1; i >= 0; i-- {
+		f := fills[i]
+		if f.Qty <= 0 { continue }
+		if f.Side == exchange.Buy {
+			avg = (avg*qty + f.Price*f.Qty) / (qty + f.Qty)
+			qty += f.Qty
+			continue
+		}
+		if qty -= f.Qty; qty <= 1e-12 { qty, avg = 0, 0 }
+	}
+	return avg
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	paused       atomic.Bool
+	controlToken atomic.Value // string; empty disables /pause and /resume
+	metricPaused = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_paused", Help: "1 while trading is paused through /pause"})
+)
+
+// Manual pause/resume, served next to /metrics on the default mux. Both need
+// POST with "Authorization: Bearer <CONTROL_TOKEN>".
+func init() {
+	prometheus.MustRegister(metricPaused)
+	http.HandleFunc("/pause", controlHandler(true))
+	http.HandleFunc("/resume", controlHandler(false))
+}
+
+func controlHandler(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		token, _ := controlToken.Load().(string)
+		if token == "" {
+			http.Error(w, "control endpoints disabled (CONTROL_TOKEN unset)", http.StatusForbidden)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		SetPaused(pause)
+		slog.Warn("trading control", "paused", pause, "remote", r.RemoteAddr)
+		if pause {
+			_, _ = w.Write([]byte("paused\n"))
+		} else {
+			_, _ = w.Write([]byte("resumed\n"))
+		}
+	}
+}
+
+// SetControlToken sets the shared secret /pause and /resume require.
+func SetControlToken(token string) { controlToken.Store(token) }
+
+// SetPaused pauses (true) or resumes (false) order placement.
+func SetPaused(on bool) {
+	paused.Store(on)
+	if on {
+		metricPaused.Set(1)
+	} else {
+		metricPaused.Set(0)
+	}
+}
+
+// Paused reports whether trading is paused; the main loop checks it before
+// evaluating signals.
+func Paused() bool { return paused.Load() }
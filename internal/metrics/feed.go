@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricPriceStaleness = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bot_price_staleness_seconds", Help: "Age of the latest price tick, by symbol"}, []string{"symbol"})
+	metricSpread         = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bot_spread_bps", Help: "Bid/ask spread in basis points of the mid, by symbol"}, []string{"symbol"})
+)
+
+func init() { prometheus.MustRegister(metricPriceStaleness, metricSpread) }
+
+// SetPriceStaleness publishes how old symbol's latest price tick is.
+func SetPriceStaleness(symbol string, age time.Duration) {
+	metricPriceStaleness.WithLabelValues(symbol).Set(age.Seconds())
+}
+
+// SetSpreadBps publishes symbol's current bid/ask spread.
+func SetSpreadBps(symbol string, bps float64) { metricSpread.WithLabelValues(symbol).Set(bps) }
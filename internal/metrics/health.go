@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+var ready atomic.Bool
+
+// Liveness and readiness probes, served next to /metrics on the default mux.
+func init() {
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ready\n"))
+	})
+}
+
+// SetReady flips /readyz to 200 (true) or 503 (false). The bot marks itself
+// ready once it has a valid price and a successful account read.
+func SetReady(ok bool) { ready.Store(ok) }
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// StartPush pushes the default registry to a Prometheus Pushgateway under job
+// every interval, and once more when the returned stop func is called (call it
+// on shutdown). The pull endpoint from Serve keeps working alongside.
+func StartPush(url, job string, every time.Duration) (stop func()) {
+	if every <= 0 { every = 15 * time.Second }
+	p := push.New(url, job).Gatherer(prometheus.DefaultGatherer)
+	pushOnce := func() {
+		if err := p.Push(); err != nil {
+			log.Printf("[metrics] pushgateway: %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				pushOnce()
+				return
+			case <-t.C:
+				pushOnce()
+			}
+		}
+	}()
+	return func() { close(done); <-finished }
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartPushSendsJobAndMetrics(t *testing.T) {
+	type push struct {
+		method, path string
+		body         []byte
+	}
+	var mu sync.Mutex
+	var pushes []push
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushes = append(pushes, push{r.Method, r.URL.Path, b})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	SetOrdersRemainingToday(7)
+	stop := StartPush(srv.URL, "coinlila", time.Hour)
+	stop() // pushes once more on shutdown
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 {
+		t.Fatalf("%d pushes, want 1 on stop", len(pushes))
+	}
+	p := pushes[0]
+	if p.method != http.MethodPut || p.path != "/metrics/job/coinlila" {
+		t.Errorf("push %s %s, want PUT /metrics/job/coinlila", p.method, p.path)
+	}
+	for _, name := range []string{"bot_orders_remaining_today", "bot_halted", "bot_paused"} {
+		if !bytes.Contains(p.body, []byte(name)) {
+			t.Errorf("payload has no %s", name)
+		}
+	}
+}
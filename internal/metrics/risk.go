@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricOrdersRemaining = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_orders_remaining_today", Help: "Orders left in today's budget (-1 = unlimited)"})
+	metricDecisionDenied  = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "bot_decision_denied_total", Help: "Risk decisions denied, by reason"}, []string{"reason"})
+	metricDrawdown        = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_drawdown_pct", Help: "Equity drawdown (%) from the peak since day open"})
+	metricHalted          = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_halted", Help: "1 while trading is halted by the daily loss limit or a losing streak"})
+	metricLossStreak      = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_loss_streak", Help: "Closed trades lost in a row since the last winning one"})
+	metricErrorCooldown   = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_error_cooldown_seconds", Help: "Current error cooldown, grown by consecutive order errors"})
+)
+
+func init() {
+	prometheus.MustRegister(metricOrdersRemaining, metricDecisionDenied, metricDrawdown, metricHalted, metricLossStreak, metricErrorCooldown)
+}
+
+// SetOrdersRemainingToday publishes the remaining daily order budget (-1 = unlimited).
+func SetOrdersRemainingToday(n int) { metricOrdersRemaining.Set(float64(n)) }
+
+// SetDrawdownPct publishes the current drawdown from today's peak equity.
+func SetDrawdownPct(pct float64) { metricDrawdown.Set(pct) }
+
+// SetHalted publishes whether the daily loss halt is in force.
+func SetHalted(on bool) {
+	if on {
+		metricHalted.Set(1)
+	} else {
+		metricHalted.Set(0)
+	}
+}
+
+// SetLossStreak publishes the current run of losing closed trades.
+func SetLossStreak(n int) { metricLossStreak.Set(float64(n)) }
+
+// SetErrorCooldown publishes the effective error cooldown.
+func SetErrorCooldown(d time.Duration) { metricErrorCooldown.Set(d.Seconds()) }
+
+// ObserveDenial counts a denied risk decision under its stable reason code.
+func ObserveDenial(reason string) { metricDecisionDenied.WithLabelValues(reason).Inc() }
+
+// RiskMetrics publishes account performance gauges (equity, day PnL, order
+// count) so the main loop sets them through typed methods.
+type RiskMetrics struct {
+	equity      prometheus.Gauge
+	equityOpen  prometheus.Gauge
+	dayPnLPct   prometheus.Gauge
+	ordersToday prometheus.Gauge
+}
+
+// NewRiskMetrics registers the gauges with the default registry; call it once.
+func NewRiskMetrics() *RiskMetrics {
+	m := &RiskMetrics{
+		equity:      prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_equity_usd", Help: "Current account equity in USD"}),
+		equityOpen:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_equity_at_open_usd", Help: "Equity at the trading-day open in USD"}),
+		dayPnLPct:   prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_day_pnl_pct", Help: "Equity change (%) since the trading-day open"}),
+		ordersToday: prometheus.NewGauge(prometheus.GaugeOpts{Name: "bot_orders_today", Help: "Orders placed so far today"}),
+	}
+	prometheus.MustRegister(m.equity, m.equityOpen, m.dayPnLPct, m.ordersToday)
+	return m
+}
+
+// SetEquity publishes current equity and the day-open baseline, and the day PnL
+// (%) between them (0 while the baseline is unknown).
+func (m *RiskMetrics) SetEquity(nowUSD, atOpenUSD float64) {
+	m.equity.Set(nowUSD)
+	m.equityOpen.Set(atOpenUSD)
+	m.dayPnLPct.Set(DayPnLPct(nowUSD, atOpenUSD))
+}
+
+// SetOrdersToday publishes today's order count.
+func (m *RiskMetrics) SetOrdersToday(n int) { m.ordersToday.Set(float64(n)) }
+
+// DayPnLPct is the equity change (%) from atOpenUSD to nowUSD.
+func DayPnLPct(nowUSD, atOpenUSD float64) float64 {
+	if atOpenUSD <= 0 { return 0 }
+	return (nowUSD - atOpenUSD) / atOpenUSD * 100
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveDenialCountsByReason(t *testing.T) {
+	ObserveDenial("order_cap")
+	ObserveDenial("spread")
+	ObserveDenial("order_cap")
+	want := `
+# HELP bot_decision_denied_total Risk decisions denied, by reason
+# TYPE bot_decision_denied_total counter
+bot_decision_denied_total{reason="order_cap"} 2
+bot_decision_denied_total{reason="spread"} 1
+`
+	if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(want), "bot_decision_denied_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the /status payload: a human-readable glance at the bot.
+type Status struct {
+	Mode            string    `json:"mode"`
+	Symbols         []string  `json:"symbols"`
+	EquityUSD       float64   `json:"equity_usd"`
+	EquityAtOpenUSD float64   `json:"equity_at_open_usd"`
+	DayPnLPct       float64   `json:"day_pnl_pct"`
+	OrdersToday     int       `json:"orders_today"`
+	OrdersRemaining int       `json:"orders_remaining_today"` // left in today's budget (-1 = unlimited)
+	BreakerState    string    `json:"breaker_state"`
+	BreakerRetryIn  float64   `json:"breaker_retry_in_sec,omitempty"` // until an open breaker goes half-open
+	OrdersInWindow  int       `json:"orders_in_rate_window"`
+	OpenOrders      int       `json:"open_orders"` // resting limit and stop orders not yet filled
+	Halted          bool      `json:"halted"`
+	Paused          bool      `json:"paused"`
+	LastTick        time.Time `json:"last_tick"`
+}
+
+var (
+	statusMu sync.Mutex
+	status   Status
+)
+
+// JSON status for dashboards, served next to /metrics on the default mux.
+func init() {
+	http.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		statusMu.Lock()
+		s := status
+		statusMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s)
+	})
+}
+
+// SetStatus replaces the snapshot /status serves; the main loop calls it each tick.
+func SetStatus(s Status) {
+	statusMu.Lock()
+	status = s
+	statusMu.Unlock()
+}
@@ -0,0 +1,25 @@
+package notify
+
+import "log/slog"
+
+// FillReporter logs executed orders with a prefix that makes live fills stand out
+// from paper ones and, when EscalateLive is set, also sends every live fill to the
+// notifier (useful during the paper→live rollout).
+type FillReporter struct {
+	Live         bool
+	EscalateLive bool
+	N            Notifier
+}
+
+// Report logs msg (e.g. "BUY 0.01 @ 65000.00") with optional structured key/value
+// attrs, and escalates live fills when enabled.
+func (f FillReporter) Report(msg string, attrs ...any) {
+	if !f.Live {
+		slog.Info("[paper] "+msg, append(attrs, "live", false)...)
+		return
+	}
+	slog.Warn("[LIVE FILL] "+msg, append(attrs, "live", true)...)
+	if f.EscalateLive && f.N != nil {
+		f.N.Notify(Warn, "live order filled: "+msg)
+	}
+}
@@ -0,0 +1,30 @@
+package notify
+
+import "testing"
+
+type recorder struct{ msgs []string }
+
+func (r *recorder) Notify(level Level, msg string) { r.msgs = append(r.msgs, string(level)+": "+msg) }
+
+func TestFillReporterEscalatesLiveFillsOnly(t *testing.T) {
+	const fill = "BTC-USD BUY 0.01 @ 65000.00"
+	for _, c := range []struct {
+		name           string
+		live, escalate bool
+		want           int
+	}{
+		{"paper", false, true, 0},
+		{"live, escalation off", true, false, 0},
+		{"live, escalation on", true, true, 1},
+	} {
+		r := &recorder{}
+		FillReporter{Live: c.live, EscalateLive: c.escalate, N: r}.Report(fill, "symbol", "BTC-USD")
+		if len(r.msgs) != c.want {
+			t.Errorf("%s: %d notifications %q, want %d", c.name, len(r.msgs), r.msgs, c.want)
+			continue
+		}
+		if c.want == 1 && r.msgs[0] != "warn: live order filled: "+fill {
+			t.Errorf("%s: notified %q", c.name, r.msgs[0])
+		}
+	}
+}
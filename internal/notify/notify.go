@@ -0,0 +1,57 @@
+// Package notify delivers operator alerts: always to the log, optionally to a webhook.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Level is the alert severity.
+type Level string
+
+const (
+	Info     Level = "info"
+	Warn     Level = "warn"
+	Critical Level = "critical"
+)
+
+// Notifier sends an operator alert.
+type Notifier interface {
+	Notify(level Level, msg string)
+}
+
+// New returns a webhook notifier when url is set, else a log-only notifier.
+func New(url string) Notifier {
+	if url == "" {
+		return Log{}
+	}
+	return &Webhook{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Log writes alerts to the process log.
+type Log struct{}
+
+func (Log) Notify(level Level, msg string) { log.Printf("[alert:%s] %s", level, msg) }
+
+// Webhook posts {"level": ..., "text": ...} JSON to URL (async, best-effort)
+// and mirrors every alert to the log.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(level Level, msg string) {
+	Log{}.Notify(level, msg)
+	b, _ := json.Marshal(map[string]string{"level": string(level), "text": msg})
+	go func() {
+		resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(b))
+		if err != nil {
+			log.Printf("[notify] webhook failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
@@ -0,0 +1,130 @@
+package risk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RiskBucket is one strategy's carve-out of the daily limits. Zero fields are
+// unlimited within the bucket; the account-wide Limits still bound the total.
+type RiskBucket struct {
+	MaxOrdersPerDay int
+	MaxNotionalUSD  float64 // notional the strategy may trade per day
+
+	ordersToday   int
+	notionalToday float64
+}
+
+// Buckets holds per-strategy sub-budgets keyed by strategy ID.
+type Buckets struct {
+	mu sync.Mutex
+	m  map[string]*RiskBucket
+}
+
+// ParseBuckets parses "id:orders:notional,..." (e.g. "sma:20:5000,rsi:10:2000").
+func ParseBuckets(spec string) (*Buckets, error) {
+	b := &Buckets{m: map[string]*RiskBucket{}}
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		f := strings.Split(part, ":")
+		if len(f) != 3 || f[0] == "" {
+			return nil, fmt.Errorf("risk buckets: %q is not id:orders:notional", part)
+		}
+		orders, err := strconv.Atoi(f[1])
+		if err != nil || orders < 0 {
+			return nil, fmt.Errorf("risk buckets: bad order count %q", f[1])
+		}
+		notional, err := strconv.ParseFloat(f[2], 64)
+		if err != nil || notional < 0 {
+			return nil, fmt.Errorf("risk buckets: bad notional %q", f[2])
+		}
+		b.m[f[0]] = &RiskBucket{MaxOrdersPerDay: orders, MaxNotionalUSD: notional}
+	}
+	return b, nil
+}
+
+// Check denies an allowed decision when the strategy's bucket is exhausted and
+// shrinks it to the bucket's remaining notional otherwise.
+func (b *Buckets) Check(dec Decision, strategyID string, price float64) Decision {
+	if b == nil || !dec.Allow {
+		return dec
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bk, ok := b.m[strategyID]
+	if !ok {
+		return dec
+	}
+	if bk.MaxOrdersPerDay > 0 && bk.ordersToday >= bk.MaxOrdersPerDay {
+		return deny(DenyBucket, "strategy "+strategyID+" order budget exhausted")
+	}
+	if bk.MaxNotionalUSD > 0 {
+		left := bk.MaxNotionalUSD - bk.notionalToday
+		if left <= 0 {
+			return deny(DenyBucket, "strategy "+strategyID+" notional budget exhausted")
+		}
+		if dec.NotionalUSD > left && price > 0 {
+			dec.NotionalUSD, dec.Qty = left, left/price
+		}
+	}
+	return dec
+}
+
+// Note charges an executed order to the strategy's bucket.
+func (b *Buckets) Note(strategyID string, notionalUSD float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if bk, ok := b.m[strategyID]; ok {
+		bk.ordersToday++
+		bk.notionalToday += notionalUSD
+	}
+}
+
+// Usage returns each bucket's orders and notional used today, for the day
+// snapshot.
+func (b *Buckets) Usage() (orders map[string]int, notionalUSD map[string]float64) {
+	if b == nil {
+		return nil, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	orders, notionalUSD = map[string]int{}, map[string]float64{}
+	for id, bk := range b.m {
+		if bk.ordersToday > 0 || bk.notionalToday > 0 {
+			orders[id], notionalUSD[id] = bk.ordersToday, bk.notionalToday
+		}
+	}
+	return orders, notionalUSD
+}
+
+// RestoreUsage sets today's usage from a snapshot, so a restart doesn't refill
+// the buckets. Strategies no longer configured are ignored.
+func (b *Buckets) RestoreUsage(orders map[string]int, notionalUSD map[string]float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, bk := range b.m {
+		bk.ordersToday, bk.notionalToday = orders[id], notionalUSD[id]
+	}
+}
+
+// ResetDay clears usage at the trading-day boundary.
+func (b *Buckets) ResetDay() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, bk := range b.m {
+		bk.ordersToday, bk.notionalToday = 0, 0
+	}
+}
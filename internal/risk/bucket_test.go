@@ -0,0 +1,70 @@
+package risk
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBucketExhaustedWhileAnotherTrades(t *testing.T) {
+	b, err := ParseBuckets("sma:2:0,rsi:0:150")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := Decision{Allow: true, NotionalUSD: 100, Qty: 1}
+	for i := 0; i < 2; i++ {
+		if dec := b.Check(allowed, "sma", 100); !dec.Allow {
+			t.Fatalf("sma order %d = %+v, want allowed", i+1, dec)
+		}
+		b.Note("sma", 100)
+	}
+	if dec := b.Check(allowed, "sma", 100); dec.Allow || dec.Code != DenyBucket {
+		t.Errorf("third sma order = %+v, want denied with %s", dec, DenyBucket)
+	}
+	// rsi has its own budget: 150 USD, so the second order is cut to what is left
+	if dec := b.Check(allowed, "rsi", 100); !dec.Allow || dec.NotionalUSD != 100 {
+		t.Fatalf("rsi order = %+v, want 100 USD allowed", dec)
+	}
+	b.Note("rsi", 100)
+	if dec := b.Check(allowed, "rsi", 100); !dec.Allow || dec.NotionalUSD != 50 || dec.Qty != 0.5 {
+		t.Errorf("second rsi order = %+v, want cut to 50 USD", dec)
+	}
+	// a strategy without a bucket is bounded only by the account limits
+	if dec := b.Check(allowed, "macd", 100); dec != allowed {
+		t.Errorf("unbudgeted order = %+v, want it unchanged", dec)
+	}
+}
+
+// Bucket usage is part of the day snapshot: a same-day restart keeps it, the
+// next day starts over.
+func TestBucketUsageSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "day_snapshot.json")
+	now := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	start := func(at time.Time) (*Buckets, *DayManager, *State) {
+		b, _ := ParseBuckets("sma:2:0,rsi:0:150")
+		dm := NewDayManager("UTC", path)
+		dm.Buckets = b
+		rs := NewState(10000, 0, at)
+		dm.InitAtStartup(at, 10000, rs)
+		return b, dm, rs
+	}
+	b, dm, rs := start(now)
+	b.Note("sma", 100)
+	b.Note("sma", 100)
+	b.Note("rsi", 120)
+	dm.PersistProgress(now, rs)
+
+	b, _, _ = start(now.Add(time.Hour))
+	allowed := Decision{Allow: true, NotionalUSD: 100, Qty: 1}
+	if dec := b.Check(allowed, "sma", 100); dec.Allow {
+		t.Errorf("sma refilled by a restart: %+v", dec)
+	}
+	if dec := b.Check(allowed, "rsi", 100); !dec.Allow || dec.NotionalUSD != 30 {
+		t.Errorf("rsi after restart = %+v, want 30 USD left", dec)
+	}
+
+	b, _, _ = start(now.Add(24 * time.Hour))
+	if dec := b.Check(allowed, "sma", 100); !dec.Allow {
+		t.Errorf("sma still exhausted the next day: %+v", dec)
+	}
+}
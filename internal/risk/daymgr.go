@@ -1,7 +1,8 @@
 package risk
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/chidi150c/coinlila/internal/util"
@@ -9,7 +10,19 @@ import (
 
 type DayManager struct {
 	TZ          string
+	Open        time.Duration // trading-day open after local midnight in TZ (0 = midnight)
 	Path        string // snapshot file path
+
+	// RecoveryLossPct is the interim loss cap applied for the rest of the day when
+	// both the snapshot and its .bak are missing (equity-at-open unknown). 0 = off.
+	RecoveryLossPct float64
+	// MaxStaleDays is how many trading days old a loaded snapshot may be before it
+	// is treated as stale (a gap in rollovers) and reseeded conservatively. 0 = 1.
+	MaxStaleDays    int
+	Alert           func(msg string) // optional operator alert hook
+	// Buckets, when set, has its per-strategy usage saved with the day's
+	// progress, restored on a same-day restart and cleared at rollover.
+	Buckets         *Buckets
 }
 
 func NewDayManager(tz, path string) *DayManager {
@@ -21,58 +34,158 @@ func NewDayManager(tz, path string) *DayManager {
 // Returns the loaded snapshot and the chosen EquityAtOpen.
 func (dm *DayManager) InitAtStartup(now time.Time, equityNow float64, rs *State) (util.DaySnapshot, float64) {
 	// default: seed from now
-	seed := util.SeedForToday(dm.TZ, now, equityNow)
+	seed := util.SeedForToday(dm.TZ, dm.Open, now, equityNow)
 
 	snap, err := util.LoadSnapshot(dm.Path)
 	if err != nil {
+		snap, err = util.LoadSnapshot(dm.Path + ".bak")
+		if err == nil {
+			slog.Warn("day snapshot unreadable, recovered from .bak", "path", dm.Path)
+		}
+	}
+	if err != nil {
+		// Both files gone: today's true equity-at-open is unknown, so current
+		// equity is only a stand-in. Optionally trade under a tighter cap.
+		if dm.RecoveryLossPct > 0 {
+			seed.InterimMaxLossPct = dm.RecoveryLossPct
+		}
+		dm.syncPeriods(now, equityNow, rs)
+		seed = withPeriods(seed, rs)
 		_ = util.SaveSnapshot(dm.Path, seed)
-		rs.ResetDay(seed.EquityAtOpenUSD, util.TodayOpen(dm.TZ, now))
-		log.Printf("[daymgr] seeded snapshot for today (tz=%s)", dm.TZ)
+		rs.ResetDay(seed.EquityAtOpenUSD, util.TodayOpen(dm.TZ, dm.Open, now))
+		rs.SetInterimMaxLossPct(seed.InterimMaxLossPct)
+		slog.Info("day snapshot seeded", "tz", dm.TZ, "equity_open", seed.EquityAtOpenUSD)
+		if seed.InterimMaxLossPct > 0 {
+			dm.alert(fmt.Sprintf("day snapshot and .bak missing; equity_open reset to %.2f, interim loss cap %.2f%% until rollover",
+				seed.EquityAtOpenUSD, seed.InterimMaxLossPct))
+		}
 		return seed, seed.EquityAtOpenUSD
 	}
 
+	restorePeriods(snap, rs)
+	dm.syncPeriods(now, equityNow, rs)
+	rs.RestoreEntries(snap.Entries) // positions outlive the day, unlike peaks
+
 	dayOpenPrev, err := util.ParseDayOpenISO(snap.DayOpenISO)
-	if err != nil || !util.SameTradingDay(dm.TZ, dayOpenPrev, now) {
+	if err != nil || !util.SameTradingDay(dm.TZ, dm.Open, dayOpenPrev, now) {
 		// Old snapshot → start a fresh trading day
+		prevISO := snap.DayOpenISO
 		snap = seed
+		stale := err != nil || dm.isStale(dayOpenPrev, now)
+		if stale && dm.RecoveryLossPct > 0 {
+			snap.InterimMaxLossPct = dm.RecoveryLossPct
+		}
+		snap = withPeriods(snap, rs)
 		_ = util.SaveSnapshot(dm.Path, snap)
-		rs.ResetDay(snap.EquityAtOpenUSD, util.TodayOpen(dm.TZ, now))
-		log.Printf("[daymgr] rolled snapshot to today (tz=%s)", dm.TZ)
+		rs.ResetDay(snap.EquityAtOpenUSD, util.TodayOpen(dm.TZ, dm.Open, now))
+		rs.SetInterimMaxLossPct(snap.InterimMaxLossPct)
+		slog.Info("day snapshot rolled to today", "tz", dm.TZ, "prev_day_open", prevISO, "equity_open", snap.EquityAtOpenUSD)
+		if stale {
+			dm.alert(fmt.Sprintf("stale day snapshot (day_open=%q); reseeded equity_open=%.2f, interim loss cap %.2f%%",
+				prevISO, snap.EquityAtOpenUSD, snap.InterimMaxLossPct))
+		}
 		return snap, snap.EquityAtOpenUSD
 	}
 
 	// Same day: reuse
-	rs.ResetDay(snap.EquityAtOpenUSD, util.TodayOpen(dm.TZ, now))
-	rs.OrdersToday = snap.OrdersToday
-	rs.RealizedPnLUSD = snap.RealizedPnLUSD
-	log.Printf("[daymgr] loaded snapshot for today (tz=%s)", dm.TZ)
-	return snap, snap.EquityAtOpenUSD
+	rs.ResetDay(snap.EquityAtOpenUSD, util.TodayOpen(dm.TZ, dm.Open, now))
+	rs.RestoreOrderCounts(snap.OrdersToday, snap.OrdersBySymbol)
+	dm.Buckets.RestoreUsage(snap.BucketOrders, snap.BucketNotional)
+	rs.SetRealizedPnL(snap.RealizedPnLUSD)
+	rs.SetInterimMaxLossPct(snap.InterimMaxLossPct)
+	cause := DenialReason(snap.HaltCause)
+	if snap.Halted && cause == "" { cause = DenyDailyLoss } // snapshots before halt_cause
+	rs.RestoreHalt(snap.Halted, cause)
+	rs.RestoreLossStreak(snap.LossStreak)
+	rs.RestorePeaks(snap.Peaks)
+	if snap.Halted {
+		slog.Warn("trading was halted earlier today, staying halted until rollover", "cause", cause)
+	}
+	slog.Info("day snapshot loaded", "tz", dm.TZ, "equity_open", snap.EquityAtOpenUSD, "orders_today", snap.OrdersToday)
+	return withPeriods(snap, rs), snap.EquityAtOpenUSD
 }
 
 // RolloverIfNeeded checks the boundary; when hit, it writes a fresh snapshot with `equityNow` as the new EquityAtOpenUSD,
 // resets the risk state, and returns true. Call this once per tick.
 func (dm *DayManager) RolloverIfNeeded(now time.Time, equityNow float64, rs *State) bool {
-	if util.SameTradingDay(dm.TZ, rs.DayOpen, now) {
+	if util.SameTradingDay(dm.TZ, dm.Open, rs.DayOpen(), now) {
 		return false
 	}
-	// Crossed into a new trading day
-	newSnap := util.SeedForToday(dm.TZ, now, equityNow)
+	// Crossed into a new trading day (and possibly a new week/month)
+	dm.syncPeriods(now, equityNow, rs)
+	newSnap := withPeriods(util.SeedForToday(dm.TZ, dm.Open, now, equityNow), rs)
 	if err := util.SaveSnapshot(dm.Path, newSnap); err != nil {
-		log.Printf("[daymgr] ERROR saving new snapshot: %v", err)
+		slog.Error("saving day snapshot failed", "path", dm.Path, "err", err)
 	}
-	rs.ResetDay(equityNow, util.TodayOpen(dm.TZ, now))
-	log.Printf("[daymgr] new trading day started; equity_open=%.2f", equityNow)
+	rs.ResetDay(equityNow, util.TodayOpen(dm.TZ, dm.Open, now))
+	dm.Buckets.ResetDay()
+	slog.Info("day rollover", "tz", dm.TZ, "day_open", rs.DayOpen(), "equity_open", equityNow)
 	return true
 }
 
 // PersistProgress can be called periodically to keep OrdersToday/RealizedPnL durable.
 func (dm *DayManager) PersistProgress(now time.Time, rs *State) {
 	snap := util.DaySnapshot{
-		DayOpenISO:      util.TodayOpen(dm.TZ, now).UTC().Format(time.RFC3339),
-		Timezone:        dm.TZ,
-		EquityAtOpenUSD: rs.EquityAtOpenUSD,
-		OrdersToday:     rs.OrdersToday,
-		RealizedPnLUSD:  rs.RealizedPnLUSD,
+		DayOpenISO:        util.TodayOpen(dm.TZ, dm.Open, now).UTC().Format(time.RFC3339),
+		Timezone:          dm.TZ,
+		EquityAtOpenUSD:   rs.EquityAtOpen(),
+		OrdersToday:       rs.OrdersToday(),
+		OrdersBySymbol:    rs.OrderCounts(),
+		RealizedPnLUSD:    rs.RealizedPnL(),
+		InterimMaxLossPct: rs.InterimMaxLossPct(),
+		Halted:            rs.Halted(),
+		HaltCause:         string(rs.HaltCause()),
+		LossStreak:        rs.LossStreak(),
+		Peaks:             rs.Peaks(),
+		Entries:           rs.Entries(),
 	}
-	_ = util.SaveSnapshot(dm.Path, snap) // best-effort
+	snap.BucketOrders, snap.BucketNotional = dm.Buckets.Usage()
+	_ = util.SaveSnapshot(dm.Path, withPeriods(snap, rs)) // best-effort
+}
+
+// restorePeriods loads the weekly/monthly anchors saved in snap into rs.
+func restorePeriods(snap util.DaySnapshot, rs *State) {
+	if t, err := util.ParseDayOpenISO(snap.WeekOpenISO); err == nil {
+		rs.SetWeekOpen(t, snap.EquityAtWeekOpen)
+	}
+	if t, err := util.ParseDayOpenISO(snap.MonthOpenISO); err == nil {
+		rs.SetMonthOpen(t, snap.EquityAtMonthOpen)
+	}
+}
+
+// syncPeriods re-anchors the weekly/monthly baselines at equityNow when now is
+// in a different ISO week / calendar month than rs's anchor, or none is set
+// (e.g. a snapshot written before these fields existed).
+func (dm *DayManager) syncPeriods(now time.Time, equityNow float64, rs *State) {
+	wk, mo := util.WeekOpen(dm.TZ, dm.Open, now), util.MonthOpen(dm.TZ, dm.Open, now)
+	if at, eq := rs.WeekOpen(); !at.Equal(wk) || eq <= 0 {
+		rs.SetWeekOpen(wk, equityNow)
+	}
+	if at, eq := rs.MonthOpen(); !at.Equal(mo) || eq <= 0 {
+		rs.SetMonthOpen(mo, equityNow)
+	}
+}
+
+// withPeriods copies rs's weekly/monthly anchors into snap.
+func withPeriods(snap util.DaySnapshot, rs *State) util.DaySnapshot {
+	wk, eqWk := rs.WeekOpen()
+	mo, eqMo := rs.MonthOpen()
+	snap.WeekOpenISO, snap.EquityAtWeekOpen = wk.UTC().Format(time.RFC3339), eqWk
+	snap.MonthOpenISO, snap.EquityAtMonthOpen = mo.UTC().Format(time.RFC3339), eqMo
+	return snap
+}
+
+// isStale reports whether the snapshot's day is more than MaxStaleDays trading
+// days before now (or in the future, which can only mean a bad clock/file).
+func (dm *DayManager) isStale(dayOpenPrev, now time.Time) bool {
+	maxDays := dm.MaxStaleDays
+	if maxDays <= 0 { maxDays = 1 }
+	gap := util.TodayOpen(dm.TZ, dm.Open, now).Sub(util.TodayOpen(dm.TZ, dm.Open, dayOpenPrev))
+	days := int(gap.Round(24*time.Hour) / (24 * time.Hour)) // rounding absorbs DST hours
+	return days < 0 || days > maxDays
+}
+
+func (dm *DayManager) alert(msg string) {
+	slog.Warn("daymgr alert", "alert", msg)
+	if dm.Alert != nil { dm.Alert(msg) }
 }
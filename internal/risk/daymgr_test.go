@@ -0,0 +1,114 @@
+package risk
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The trailing stop continues from the peak saved before a same-day restart;
+// the next day's restart keeps the entry but starts the peak over.
+func TestTrailingPeakSurvivesRestart(t *testing.T) {
+	dm := NewDayManager("UTC", filepath.Join(t.TempDir(), "day_snapshot.json"))
+	lim := Limits{TrailingStopPct: 5}
+	now := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+
+	rs := NewState(10000, 0, now)
+	dm.InitAtStartup(now, 10000, rs)
+	rs.NoteFill("BTC-USD", true, 1, 100)
+	rs.TrailingStopTriggered(lim, "BTC-USD", 120)
+	dm.PersistProgress(now, rs)
+
+	restarted := NewState(10000, 0, now)
+	dm.InitAtStartup(now.Add(time.Hour), 10000, restarted)
+	if p, e := restarted.Peak("BTC-USD"), restarted.EntryPrice("BTC-USD"); p != 120 || e != 100 {
+		t.Fatalf("restored peak %v entry %v, want 120 and 100", p, e)
+	}
+	if restarted.TrailingStopTriggered(lim, "BTC-USD", 115) {
+		t.Errorf("stop fired at 115, above 5%% under the restored 120 peak")
+	}
+	if !restarted.TrailingStopTriggered(lim, "BTC-USD", 114) {
+		t.Errorf("stop did not fire at 114, 5%% under the restored 120 peak")
+	}
+
+	nextDay := NewState(10000, 0, now)
+	dm.InitAtStartup(now.Add(24*time.Hour), 10000, nextDay)
+	if p, e := nextDay.Peak("BTC-USD"), nextDay.EntryPrice("BTC-USD"); p != 0 || e != 100 {
+		t.Errorf("next day: peak %v entry %v, want 0 and 100", p, e)
+	}
+}
+
+// With the snapshot and its .bak both gone, equity-at-open is unknown: the day
+// reseeds from current equity under the tighter interim cap, and says so.
+func TestBothSnapshotsMissingTradesUnderInterimCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "day_snapshot.json")
+	dm := NewDayManager("UTC", path)
+	dm.RecoveryLossPct = 2
+	var alerts []string
+	dm.Alert = func(msg string) { alerts = append(alerts, msg) }
+	now := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+
+	rs := NewState(0, 0, now)
+	snap, eqOpen := dm.InitAtStartup(now, 9000, rs)
+	if eqOpen != 9000 || snap.InterimMaxLossPct != 2 || rs.InterimMaxLossPct() != 2 {
+		t.Fatalf("equity_open %v, interim cap %v/%v; want 9000 and 2", eqOpen, snap.InterimMaxLossPct, rs.InterimMaxLossPct())
+	}
+	if len(alerts) != 1 {
+		t.Errorf("%d alerts, want 1", len(alerts))
+	}
+	rs.UpdateEquity(8800) // 2.2% down: fine under a 5% daily cap, not under the interim 2%
+	if !rs.CheckHalt(now, Limits{MaxLossPctDay: 5}) {
+		t.Error("interim cap did not halt a 2.2% loss")
+	}
+
+	// the reseeded snapshot keeps the cap across a restart the same day
+	restarted := NewState(0, 0, now)
+	dm.InitAtStartup(now.Add(time.Hour), 8800, restarted)
+	if restarted.InterimMaxLossPct() != 2 || restarted.EquityAtOpen() != 9000 {
+		t.Errorf("after restart: interim cap %v, equity_open %v; want 2 and 9000",
+			restarted.InterimMaxLossPct(), restarted.EquityAtOpen())
+	}
+	if !dm.RolloverIfNeeded(now.Add(24*time.Hour), 8800, restarted) || restarted.InterimMaxLossPct() != 0 {
+		t.Errorf("interim cap %v after rollover, want 0", restarted.InterimMaxLossPct())
+	}
+}
+
+// A snapshot several days old means rollovers were missed: the day reseeds
+// from current equity under the interim cap and alerts. Yesterday's is routine.
+func TestMultiDayOldSnapshotIsStale(t *testing.T) {
+	for _, c := range []struct {
+		gap   time.Duration
+		stale bool
+	}{
+		{24 * time.Hour, false},
+		{4 * 24 * time.Hour, true},
+	} {
+		dm := NewDayManager("UTC", filepath.Join(t.TempDir(), "day_snapshot.json"))
+		dm.RecoveryLossPct = 2
+		var alerts []string
+		dm.Alert = func(msg string) { alerts = append(alerts, msg) }
+		then := time.Date(2024, 3, 8, 15, 0, 0, 0, time.UTC)
+
+		rs := NewState(0, 0, then)
+		dm.InitAtStartup(then, 10000, rs)
+		rs.CountOrder("BTC-USD")
+		rs.AddRealizedPnL(-50)
+		dm.PersistProgress(then, rs)
+		alerts = nil // the first start had no snapshot at all
+
+		later := NewState(0, 0, then)
+		snap, eqOpen := dm.InitAtStartup(then.Add(c.gap), 9500, later)
+		if eqOpen != 9500 || later.OrdersToday() != 0 || later.RealizedPnL() != 0 {
+			t.Errorf("%v later: equity_open %v, %d orders, realized %v; want a fresh day at 9500",
+				c.gap, eqOpen, later.OrdersToday(), later.RealizedPnL())
+		}
+		wantCap := 0.0
+		if c.stale { wantCap = 2 }
+		if snap.InterimMaxLossPct != wantCap || later.InterimMaxLossPct() != wantCap {
+			t.Errorf("%v later: interim cap %v/%v, want %v", c.gap, snap.InterimMaxLossPct, later.InterimMaxLossPct(), wantCap)
+		}
+		if stale := len(alerts) > 0; stale != c.stale {
+			t.Errorf("%v later: alerts %q, want stale=%v", c.gap, alerts, c.stale)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// DecideBuy sizes a buy of symbol against the limits given current exposure
+// (posUSD); symbol's PerSymbol entry, if any, overrides the global caps. Adds to
+// an open position are limited by MaxScaleIns and MaxAvgEntryDriftPct.
+// avgEntry is the position's average entry (0 = flat or unknown); the
+// position's unrealized PnL at price is reported in the Decision.
+func DecideBuy(rs *State, lim Limits, symbol string, price, posUSD, avgEntry float64) Decision {
+	dec := RoundQty(decideBuy(rs, lim, symbol, price, posUSD), lim, symbol, price)
+	dec = minTrade(capScaleIn(rs, lim, symbol, price, dec), lim)
+	if price > 0 { dec.UnrealizedPnLUSD = unrealizedPnL(posUSD/price, avgEntry, price) }
+	return dec
+}
+
+// unrealizedPnL is what qty bought at avgEntry is up (or down) at price; 0
+// without an entry.
+func unrealizedPnL(qty, avgEntry, price float64) float64 {
+	if qty <= 0 || avgEntry <= 0 || price <= 0 {
+		return 0
+	}
+	return qty * (price - avgEntry)
+}
+
+func decideBuy(rs *State, lim Limits, symbol string, price, posUSD float64) Decision {
+	if price <= 0 {
+		return deny(DenyNoPrice, "no price")
+	}
+	now := time.Now()
+	if !rs.CanAct(now) {
+		return deny(DenyCooldown, "error cooldown active")
+	}
+	if rs.CheckHalt(now, lim) {
+		return rs.haltDenial()
+	}
+	// giving back intraday gains only stops new risk: unlike the daily loss
+	// breach it does not halt, so sells and protective exits still run
+	if lim.MaxDrawdownPct > 0 && rs.DrawdownPct() >= lim.MaxDrawdownPct {
+		return deny(DenyDrawdown, fmt.Sprintf("drawdown %.2f%% from today's peak breaches %.2f%%", rs.DrawdownPct(), lim.MaxDrawdownPct))
+	}
+	sl := lim.ForSymbol(symbol)
+	if d, capped := orderCap(rs, lim, sl, symbol); capped {
+		return d
+	}
+
+	headroom := sl.MaxPositionUSD - posUSD
+	if headroom <= 0 {
+		return deny(DenyPositionCap, "position cap reached")
+	}
+	notional := headroom
+	if lim.MaxOrderNotionalUSD > 0 && notional > lim.MaxOrderNotionalUSD {
+		notional = lim.MaxOrderNotionalUSD
+	}
+	// volatility targeting: qty*price*vol ≈ equity*TargetRiskBp/1e4, within the caps;
+	// while vol is still 0 (warming up) the fixed sizing below applies
+	volOn := lim.VolSizingOn || lim.SizingMode == SizingVol
+	if vol := rs.VolFor(symbol, lim.VolMethod); volOn && lim.TargetRiskBp > 0 && vol > 0 && rs.EquityNow() > 0 {
+		target := rs.EquityNow() * lim.TargetRiskBp / 10000 / vol
+		if target < notional {
+			notional = target
+		}
+		return Decision{Allow: true, NotionalUSD: notional, Qty: notional / price,
+			Reason: fmt.Sprintf("vol sizing: equity %.2f × %.0fbp / vol %.5f = %.2f, capped to %.2f",
+				rs.EquityNow(), lim.TargetRiskBp, vol, target, notional)}
+	}
+	// Kelly: a fraction of the Kelly-optimal share of equity, estimated from recent
+	// trade outcomes; until enough trades have closed, fixed sizing applies
+	var note string
+	if lim.SizingMode == SizingKelly {
+		f, w, r, n, ok := rs.KellyEstimate(lim.KellyMinTrades)
+		if ok {
+			frac := lim.KellyFraction
+			if frac <= 0 { frac = 1 }
+			target := rs.EquityNow() * f * frac
+			if target <= 0 {
+				return deny(DenyNoEdge, fmt.Sprintf("kelly sizing: no edge over last %d trades (win rate %.0f%%, payoff %.2f)", n, w*100, r))
+			}
+			if target < notional {
+				notional = target
+			}
+			return Decision{Allow: true, NotionalUSD: notional, Qty: notional / price,
+				Reason: fmt.Sprintf("kelly sizing: win rate %.0f%%, payoff %.2f over %d trades, f*=%.3f × %.2f × equity %.2f = %.2f, capped to %.2f",
+					w*100, r, n, f, frac, rs.EquityNow(), target, notional)}
+		}
+		need := lim.KellyMinTrades
+		if need <= 0 { need = 10 }
+		note = fmt.Sprintf("kelly sizing needs %d completed trades, have %d: fixed sizing", need, n)
+	}
+	dec := fixedSize(lim, price, notional)
+	if note != "" {
+		dec.Reason = strings.TrimSuffix(note+"; "+dec.Reason, "; ")
+	}
+	return dec
+}
+
+// fixedSize is the non-adaptive sizing: FixedNotionalUSD, or the base quantity,
+// or else the full capped notional.
+func fixedSize(lim Limits, price, notional float64) Decision {
+	// fixed quote-currency size (e.g. always $25), still clamped by the caps above
+	if lim.FixedNotionalUSD > 0 {
+		fixed := lim.FixedNotionalUSD
+		if fixed > notional {
+			fixed = notional
+		}
+		return Decision{Allow: true, NotionalUSD: fixed, Qty: fixed / price,
+			Reason: fmt.Sprintf("fixed notional sizing: %.2f USD, capped to %.2f", lim.FixedNotionalUSD, fixed)}
+	}
+	if lim.SizingMode == SizingBase && lim.FixedBaseQty > 0 && lim.FixedBaseQty*price < notional {
+		return Decision{Allow: true, NotionalUSD: lim.FixedBaseQty * price, Qty: lim.FixedBaseQty}
+	}
+	return Decision{Allow: true, NotionalUSD: notional, Qty: notional / price}
+}
+
+// DecideSignalSell is DecideSell for a strategy sell signal: it first scales
+// the position down to the part lim.SellMode exits, so a signal can scale out
+// instead of closing everything. Unlike a protective exit, a signal sell is
+// denied with DenyOrderCap once the daily order caps are used up.
+func DecideSignalSell(rs *State, lim Limits, symbol string, price, posQty, avgEntry float64) Decision {
+	if d, capped := orderCap(rs, lim, lim.ForSymbol(symbol), symbol); capped {
+		return d
+	}
+	dec := DecideSell(rs, lim, symbol, price, scaleOutQty(lim, symbol, price, posQty), avgEntry)
+	dec.UnrealizedPnLUSD = unrealizedPnL(posQty, avgEntry, price) // the whole position's, not the slice sold
+	return dec
+}
+
+// scaleOutQty is the part of posQty a sell signal exits under lim.SellMode. If
+// what would remain is too small to sell later (below MinTradeUSD or the venue
+// minimum), the whole position goes instead of leaving dust behind.
+func scaleOutQty(lim Limits, symbol string, price, posQty float64) float64 {
+	qty := posQty
+	switch lim.SellMode {
+	case SellFraction:
+		if lim.SellFraction > 0 && lim.SellFraction < 1 { qty = posQty * lim.SellFraction }
+	case SellNotional:
+		if lim.SellNotionalUSD > 0 && price > 0 { qty = math.Min(posQty, lim.SellNotionalUSD/price) }
+	}
+	rest := posQty - qty
+	if rest <= 0 {
+		return posQty
+	}
+	if (lim.MinTradeUSD > 0 && rest*price < lim.MinTradeUSD) || rest < lim.Instruments[symbol].MinQty {
+		return posQty
+	}
+	return qty
+}
+
+// DecideSell sizes a reducing sell of symbol; it never suggests more than posQty.
+// Like DecideBuy, the size is floored to the symbol's lot step (see RoundQty).
+// Stops and take-profits sell through it, so the daily order caps do not apply:
+// a protective exit is never blocked (DecideSignalSell applies them).
+// avgEntry is as for DecideBuy; UnrealizedPnLUSD is that of posQty.
+func DecideSell(rs *State, lim Limits, symbol string, price, posQty, avgEntry float64) Decision {
+	dec := minTrade(RoundQty(decideSell(rs, lim, symbol, price, posQty), lim, symbol, price), lim)
+	dec.UnrealizedPnLUSD = unrealizedPnL(posQty, avgEntry, price)
+	return dec
+}
+
+func decideSell(rs *State, lim Limits, symbol string, price, posQty float64) Decision {
+	if price <= 0 {
+		return deny(DenyNoPrice, "no price")
+	}
+	if posQty <= 0 {
+		return deny(DenyNoPosition, "no position")
+	}
+	if !rs.CanAct(time.Now()) {
+		return deny(DenyCooldown, "error cooldown active")
+	}
+	if rs.CheckHalt(time.Now(), lim) {
+		return rs.haltDenial()
+	}
+
+	qty := posQty
+	if lim.SizingMode == SizingBase && lim.FixedBaseQty > 0 && lim.FixedBaseQty < qty {
+		qty = lim.FixedBaseQty
+	}
+	if lim.MaxOrderNotionalUSD > 0 && qty*price > lim.MaxOrderNotionalUSD {
+		qty = lim.MaxOrderNotionalUSD / price
+	}
+	return Decision{Allow: true, NotionalUSD: qty * price, Qty: qty}
+}
+
+// CapByConcentration denies an allowed buy when it would push the symbol's marked
+// value (symUSD) above MaxConcentrationPct of the total portfolio value (totalUSD).
+func CapByConcentration(dec Decision, lim Limits, symUSD, totalUSD float64) Decision {
+	if !dec.Allow || lim.MaxConcentrationPct <= 0 || totalUSD <= 0 {
+		return dec
+	}
+	if (symUSD+dec.NotionalUSD)/totalUSD*100 > lim.MaxConcentrationPct {
+		return deny(DenyConcentration, "concentration limit reached")
+	}
+	return dec
+}
+
+// SpreadBps is the bid/ask spread in basis points of the mid (0 without a quote).
+func SpreadBps(bid, ask float64) float64 {
+	if bid <= 0 || ask <= 0 {
+		return 0
+	}
+	mid := (bid + ask) / 2
+	return (ask - bid) / mid * 10000
+}
+
+// CapBySpread denies an allowed decision while the spread exceeds lim.MaxSpreadBps:
+// a market order there pays the wide side, well away from the mid it was sized at.
+func CapBySpread(dec Decision, lim Limits, spreadBps float64) Decision {
+	if !dec.Allow || lim.MaxSpreadBps <= 0 || spreadBps <= lim.MaxSpreadBps {
+		return dec
+	}
+	return deny(DenySpread, fmt.Sprintf("spread too wide (%.1f > %.1f bps)", spreadBps, lim.MaxSpreadBps))
+}
+
+// CapByExitCooldown denies an allowed entry while `cooldown` has not elapsed since
+// lastExit (e.g. a take-profit exit), so a lingering signal can't re-open at once.
+func CapByExitCooldown(dec Decision, lastExit time.Time, cooldown time.Duration, now time.Time) Decision {
+	if !dec.Allow || cooldown <= 0 || lastExit.IsZero() || now.Sub(lastExit) >= cooldown {
+		return dec
+	}
+	return deny(DenyReentryCooldown, "re-entry cooldown after take-profit")
+}
+
+// orderCap denies once the account-wide or the symbol's daily order count is used up.
+func orderCap(rs *State, lim Limits, sl SymbolLimits, symbol string) (Decision, bool) {
+	if lim.MaxOrdersPerDay > 0 && rs.OrdersToday() >= lim.MaxOrdersPerDay {
+		return deny(DenyOrderCap, "daily order limit reached"), true
+	}
+	if _, ok := lim.PerSymbol[symbol]; ok && sl.MaxOrdersPerDay > 0 && rs.OrdersTodayFor(symbol) >= sl.MaxOrdersPerDay {
+		return deny(DenyOrderCap, "daily order limit reached for "+symbol), true
+	}
+	return Decision{}, false
+}
+
+// minTrade denies an allowed decision whose notional is below MinTradeUSD: the
+// venue would reject such a dust order anyway.
+func minTrade(dec Decision, lim Limits) Decision {
+	if dec.Allow && lim.MinTradeUSD > 0 && dec.NotionalUSD < lim.MinTradeUSD {
+		return deny(DenyMinTrade, fmt.Sprintf("below minimum trade size (%.2f < %.2f USD)", dec.NotionalUSD, lim.MinTradeUSD))
+	}
+	return dec
+}
+
+func deny(code DenialReason, reason string) Decision { return Decision{Code: code, Reason: reason} }
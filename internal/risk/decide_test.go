@@ -0,0 +1,99 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestState() *State {
+	rs := NewState(10000, 0, time.Now())
+	rs.UpdateEquity(10000)
+	return rs
+}
+
+func TestMinTradeUSD(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 1000, MaxOrderNotionalUSD: 5, MinTradeUSD: 10}
+	dec := DecideBuy(rs, lim, "BTC-USD", 100, 0, 0)
+	if dec.Allow || dec.Code != DenyMinTrade || !strings.Contains(dec.Reason, "below minimum trade size") {
+		t.Errorf("5 USD buy = %+v, want denied with %s", dec, DenyMinTrade)
+	}
+	lim.MaxOrderNotionalUSD = 50
+	if dec := DecideBuy(rs, lim, "BTC-USD", 100, 0, 0); !dec.Allow || dec.NotionalUSD != 50 {
+		t.Errorf("50 USD buy = %+v, want allowed", dec)
+	}
+	if dec := DecideSell(rs, lim, "BTC-USD", 100, 0.05, 100); dec.Allow || dec.Code != DenyMinTrade {
+		t.Errorf("5 USD sell = %+v, want denied with %s", dec, DenyMinTrade)
+	}
+	if dec := DecideSell(rs, lim, "BTC-USD", 100, 0.2, 100); !dec.Allow {
+		t.Errorf("20 USD sell = %+v, want allowed", dec)
+	}
+}
+
+func TestMaxDrawdownBlocksBuysOnly(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 1000, MaxOrderNotionalUSD: 100, MaxDrawdownPct: 5, MaxLossPctDay: 10}
+	rs.UpdateEquity(11000)
+	rs.UpdateEquity(10500) // 4.5% below the peak, 5% above the open
+	if dec := DecideBuy(rs, lim, "BTC-USD", 100, 0, 0); !dec.Allow {
+		t.Fatalf("buy at 4.5%% drawdown = %+v, want allowed", dec)
+	}
+	rs.UpdateEquity(10400) // 5.5% below the peak, still up on the day
+	if dec := DecideBuy(rs, lim, "BTC-USD", 100, 0, 0); dec.Allow || dec.Code != DenyDrawdown {
+		t.Errorf("buy at 5.5%% drawdown = %+v, want denied with %s", dec, DenyDrawdown)
+	}
+	if dec := DecideSignalSell(rs, lim, "BTC-USD", 100, 0.5, 100); !dec.Allow {
+		t.Errorf("signal sell in drawdown = %+v, want allowed", dec)
+	}
+	if dec := DecideSell(rs, lim, "BTC-USD", 100, 0.5, 100); !dec.Allow {
+		t.Errorf("protective sell in drawdown = %+v, want allowed", dec)
+	}
+	if rs.Halted() {
+		t.Errorf("drawdown halted trading")
+	}
+}
+
+func TestConcentrationBlocksOnlyTheHeavySymbol(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 5000, MaxOrderNotionalUSD: 1000, MaxConcentrationPct: 40}
+	held := map[string]float64{"BTC-USD": 3500, "ETH-USD": 500}
+	total := 10000.0
+	btc := CapByConcentration(DecideBuy(rs, lim, "BTC-USD", 100, held["BTC-USD"], 0), lim, held["BTC-USD"], total)
+	if btc.Allow || btc.Code != DenyConcentration {
+		t.Errorf("BTC buy to 45%% of the portfolio = %+v, want denied with %s", btc, DenyConcentration)
+	}
+	eth := CapByConcentration(DecideBuy(rs, lim, "ETH-USD", 100, held["ETH-USD"], 0), lim, held["ETH-USD"], total)
+	if !eth.Allow || eth.NotionalUSD != 1000 {
+		t.Errorf("ETH buy to 15%% of the portfolio = %+v, want allowed", eth)
+	}
+}
+
+func TestBaseSizingRespectsNotionalCap(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 5000, MaxOrderNotionalUSD: 1000, SizingMode: SizingBase, FixedBaseQty: 1}
+	if dec := DecideBuy(rs, lim, "SOL-USD", 100, 0, 0); !dec.Allow || dec.Qty != 1 || dec.NotionalUSD != 100 {
+		t.Errorf("1 unit at 100 = %+v, want 1 unit for 100 USD", dec)
+	}
+	// at 50000 one unit would be 50x the order cap: the cap wins
+	dec := DecideBuy(rs, lim, "BTC-USD", 50000, 0, 0)
+	if !dec.Allow || dec.NotionalUSD > 1000 || dec.Qty*50000 > 1000+1e-9 {
+		t.Errorf("1 unit at 50000 = %+v, want at most 1000 USD", dec)
+	}
+}
+
+func TestReentryBlockedUntilExitCooldownElapses(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 1000, MaxOrderNotionalUSD: 100}
+	tpExit := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	buy := DecideBuy(rs, lim, "BTC-USD", 100, 0, 0)
+	if dec := CapByExitCooldown(buy, tpExit, 10*time.Minute, tpExit.Add(time.Minute)); dec.Allow || dec.Code != DenyReentryCooldown {
+		t.Errorf("buy 1m after a take-profit = %+v, want denied with %s", dec, DenyReentryCooldown)
+	}
+	if dec := CapByExitCooldown(buy, tpExit, 10*time.Minute, tpExit.Add(10*time.Minute)); !dec.Allow {
+		t.Errorf("buy once the cooldown elapsed = %+v, want allowed", dec)
+	}
+	if dec := CapByExitCooldown(buy, time.Time{}, 10*time.Minute, tpExit); !dec.Allow {
+		t.Errorf("buy with no take-profit yet = %+v, want allowed", dec)
+	}
+}
@@ -1,58 +1,503 @@
 package risk
 
 import (
+	"fmt"
 	"math"
 	"time"
+
+	"github.com/chidi150c/coinlila/internal/util"
 )
 
 // NewState initializes risk state at day open with equity snapshot.
 func NewState(equityAtOpenUSD float64, cooldownSec int, dayOpen time.Time) *State {
 	return &State{
-		EquityAtOpenUSD: equityAtOpenUSD,
-		ErrorCooldown:   time.Duration(cooldownSec) * time.Second,
-		DayOpen:         dayOpen,
+		equityAtOpenUSD: equityAtOpenUSD,
+		errorCooldown:   time.Duration(cooldownSec) * time.Second,
+		dayOpen:         dayOpen,
 	}
 }
 
 // Error handling & cooldowns
-func (s *State) NoteError()                { s.LastErrorTime = time.Now() }
-func (s *State) CanAct(now time.Time) bool { return time.Since(s.LastErrorTime) >= s.ErrorCooldown }
+func (s *State) NoteError() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErrorTime = time.Now()
+	s.consecErrors++
+}
+
+func (s *State) NoteSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consecErrors = 0
+}
+
+func (s *State) CanAct(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastErrorTime) >= s.cooldownLocked()
+}
+
+// SetErrorCooldown sets the pause after an order error; each further consecutive
+// error doubles it up to max (max <= base = a fixed cooldown).
+func (s *State) SetErrorCooldown(base, max time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorCooldown, s.maxErrorCooldown = base, max
+}
+
+// EffectiveCooldown is the base error cooldown doubled for each consecutive error
+// after the first, capped at the max (no growth when the cap is unset).
+func (s *State) EffectiveCooldown() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cooldownLocked()
+}
+
+func (s *State) cooldownLocked() time.Duration {
+	d := s.errorCooldown
+	if s.maxErrorCooldown <= d {
+		return d
+	}
+	for i := 1; i < s.consecErrors && d < s.maxErrorCooldown; i++ {
+		d *= 2
+	}
+	if d > s.maxErrorCooldown { d = s.maxErrorCooldown }
+	return d
+}
 
 // Daily reset
 func (s *State) ResetDay(newEquity float64, newOpen time.Time) {
-	s.EquityAtOpenUSD = newEquity
-	s.EquityNowUSD = newEquity
-	s.OrdersToday = 0
-	s.RealizedPnLUSD = 0
-	s.DayOpen = newOpen
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.equityAtOpenUSD = newEquity
+	s.equityNowUSD = newEquity
+	s.peakEquityUSD = newEquity
+	s.ordersToday = 0
+	s.ordersBySymbol = nil
+	s.realizedPnLUSD = 0
+	s.interimMaxLossPct = 0
+	s.halted = false
+	s.haltCause = ""
+	s.lossStreak = 0 // a new day gets a fresh streak, or the halt would re-latch at once
+	s.dayOpen = newOpen
 	s.prices = s.prices[:0]
+	s.priceTimes = s.priceTimes[:0]
+	s.ewmaVar, s.ewmaN = 0, 0
+	s.symVol = nil
+	s.peaks = nil // trailing stops start over with the day's equity
+}
+
+// DayOpen is the open of the trading day the state is counting.
+func (s *State) DayOpen() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dayOpen
 }
 
-// Equity update
-func (s *State) UpdateEquity(current float64) { s.EquityNowUSD = current }
+// Equity update (also raises today's peak)
+func (s *State) UpdateEquity(current float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.equityNowUSD = current
+	if current > s.peakEquityUSD { s.peakEquityUSD = current }
+}
+
+// EquityNow is the equity last passed to UpdateEquity (or ResetDay).
+func (s *State) EquityNow() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.equityNowUSD
+}
+
+// RealizedPnL is today's realized PnL.
+func (s *State) RealizedPnL() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.realizedPnLUSD
+}
+
+// AddRealizedPnL books usd of realized PnL.
+func (s *State) AddRealizedPnL(usd float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.realizedPnLUSD += usd
+}
+
+// SetRealizedPnL replaces today's realized PnL (e.g. recomputed by the paper sim).
+func (s *State) SetRealizedPnL(usd float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.realizedPnLUSD = usd
+}
+
+// DrawdownPct is how far (%) equity is below its peak since day open; unlike the
+// daily loss it also catches giving back intraday gains.
+func (s *State) DrawdownPct() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peak := s.peakEquityUSD
+	if s.equityAtOpenUSD > peak { peak = s.equityAtOpenUSD }
+	if peak <= 0 || s.equityNowUSD >= peak {
+		return 0
+	}
+	return (peak - s.equityNowUSD) / peak * 100
+}
+
+// NetOfExitFees marks open positions (positionsUSD) net of the taker fee needed to
+// close them, giving a slightly lower, more conservative equity figure.
+func NetOfExitFees(equityUSD, positionsUSD, takerFeeBps float64) float64 {
+	if positionsUSD <= 0 || takerFeeBps <= 0 {
+		return equityUSD
+	}
+	return equityUSD - positionsUSD*takerFeeBps/10000
+}
 
-// Kill-switch check
+// Kill-switch check (an interim cap, when tighter, takes precedence); a limit
+// of 0 or less is off, like every other loss limit
 func (s *State) BreachDailyLoss(maxLossPct float64) bool {
-	if s.EquityAtOpenUSD <= 0 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.breachDailyLossLocked(maxLossPct)
+}
+
+func (s *State) breachDailyLossLocked(maxLossPct float64) bool {
+	if s.equityAtOpenUSD <= 0 {
+		return false
+	}
+	if s.interimMaxLossPct > 0 && (maxLossPct <= 0 || s.interimMaxLossPct < maxLossPct) {
+		maxLossPct = s.interimMaxLossPct
+	}
+	if maxLossPct <= 0 {
 		return false
 	}
-	lossPct := (s.EquityAtOpenUSD - s.EquityNowUSD) / s.EquityAtOpenUSD * 100
+	lossPct := (s.equityAtOpenUSD - s.equityNowUSD) / s.equityAtOpenUSD * 100
 	return lossPct >= maxLossPct
 }
 
-// Order counter
-func (s *State) CountOrder() { s.OrdersToday++ }
+// CheckHalt latches the halt once the daily loss limit is breached (see
+// BreachDailyLossAt) or MaxConsecutiveLosses trades in a row have lost, and
+// reports it; only ResetDay clears it.
+func (s *State) CheckHalt(now time.Time, lim Limits) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.halted {
+		return true
+	}
+	switch {
+	case s.breachDailyLossAtLocked(now, lim):
+		s.halted, s.haltCause = true, DenyDailyLoss
+	case lim.MaxConsecutiveLosses > 0 && s.lossStreak >= lim.MaxConsecutiveLosses:
+		s.halted, s.haltCause = true, DenyLossStreak
+	}
+	return s.halted
+}
+
+// haltDenial is the decision returned for any order while halted.
+func (s *State) haltDenial() Decision {
+	s.mu.Lock()
+	cause, streak := s.haltCause, s.lossStreak
+	s.mu.Unlock()
+	if cause == DenyLossStreak {
+		return deny(DenyLossStreak, fmt.Sprintf("%d consecutive losing trades", streak))
+	}
+	return deny(DenyDailyLoss, "daily loss limit hit")
+}
+
+// Halted reports whether CheckHalt has latched a halt for the rest of the day.
+func (s *State) Halted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.halted
+}
+
+// HaltCause is what latched the halt: DenyDailyLoss or DenyLossStreak ("" = not halted).
+func (s *State) HaltCause() DenialReason {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.haltCause
+}
+
+// RestoreHalt sets the latched halt, e.g. from today's snapshot.
+func (s *State) RestoreHalt(halted bool, cause DenialReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.halted, s.haltCause = halted, cause
+}
+
+// EquityAtOpen is the equity snapshot taken at day open.
+func (s *State) EquityAtOpen() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.equityAtOpenUSD
+}
+
+// InterimMaxLossPct is the tighter loss cap for the rest of the day (0 = off).
+func (s *State) InterimMaxLossPct() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interimMaxLossPct
+}
+
+// SetInterimMaxLossPct sets the interim loss cap; ResetDay clears it.
+func (s *State) SetInterimMaxLossPct(pct float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interimMaxLossPct = pct
+}
+
+// LossStreak is how many closed trades in a row have lost (see NoteTradeOutcome).
+func (s *State) LossStreak() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lossStreak
+}
+
+// RestoreLossStreak sets the losing streak, e.g. from today's snapshot.
+func (s *State) RestoreLossStreak(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lossStreak = n
+}
+
+// BreachWeeklyLoss reports whether equity is down at least maxLossPct since the
+// start of the ISO week (false when maxLossPct <= 0 or no anchor is set).
+func (s *State) BreachWeeklyLoss(maxLossPct float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return breachSince(s.equityAtWeekOpen, s.equityNowUSD, maxLossPct)
+}
+
+// BreachMonthlyLoss is BreachWeeklyLoss for the calendar month.
+func (s *State) BreachMonthlyLoss(maxLossPct float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return breachSince(s.equityAtMonthOpen, s.equityNowUSD, maxLossPct)
+}
+
+// WeekOpen is the ISO week anchor and the equity recorded at it (zero = unset).
+func (s *State) WeekOpen() (time.Time, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.weekOpen, s.equityAtWeekOpen
+}
+
+// SetWeekOpen re-anchors the weekly loss baseline; ResetDay leaves it alone.
+func (s *State) SetWeekOpen(open time.Time, equityUSD float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weekOpen, s.equityAtWeekOpen = open, equityUSD
+}
+
+// MonthOpen is WeekOpen for the calendar month.
+func (s *State) MonthOpen() (time.Time, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.monthOpen, s.equityAtMonthOpen
+}
+
+// SetMonthOpen is SetWeekOpen for the calendar month.
+func (s *State) SetMonthOpen(open time.Time, equityUSD float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.monthOpen, s.equityAtMonthOpen = open, equityUSD
+}
+
+// PeriodLossBreach names the weekly or monthly limit that is breached, or "".
+func (s *State) PeriodLossBreach(lim Limits) string {
+	switch {
+	case s.BreachMonthlyLoss(lim.MaxLossPctMonth):
+		return "monthly"
+	case s.BreachWeeklyLoss(lim.MaxLossPctWeek):
+		return "weekly"
+	}
+	return ""
+}
+
+func breachSince(open, now, maxLossPct float64) bool {
+	if maxLossPct <= 0 || open <= 0 {
+		return false
+	}
+	return (open-now)/open*100 >= maxLossPct
+}
+
+// BreachDailyLossAt applies the optional grace window after day open: inside it,
+// small losses (e.g. a fill's fees right at the open) are tolerated and only a
+// loss beyond LossCapHardPct trips; afterwards the normal cap applies.
+func (s *State) BreachDailyLossAt(now time.Time, lim Limits) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.breachDailyLossAtLocked(now, lim)
+}
+
+func (s *State) breachDailyLossAtLocked(now time.Time, lim Limits) bool {
+	if lim.LossCapGrace > 0 && now.Sub(s.dayOpen) < lim.LossCapGrace {
+		return lim.LossCapHardPct > 0 && s.breachDailyLossLocked(lim.LossCapHardPct)
+	}
+	return s.breachDailyLossLocked(lim.MaxLossPctDay)
+}
+
+// Order counter (total and per symbol)
+func (s *State) CountOrder(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ordersToday++
+	if s.ordersBySymbol == nil { s.ordersBySymbol = map[string]int{} }
+	s.ordersBySymbol[symbol]++
+}
+
+// OrdersToday is the number of orders counted today.
+func (s *State) OrdersToday() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ordersToday
+}
+
+// RestoreOrderCounts reloads today's total and per-symbol counters (e.g. from a
+// snapshot).
+func (s *State) RestoreOrderCounts(total int, bySymbol map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ordersToday = total
+	s.ordersBySymbol = map[string]int{}
+	for k, v := range bySymbol { s.ordersBySymbol[k] = v }
+}
+
+// OrderCounts returns a copy of today's per-symbol order counters.
+func (s *State) OrderCounts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]int, len(s.ordersBySymbol))
+	for k, v := range s.ordersBySymbol { out[k] = v }
+	return out
+}
+
+// RemainingOrdersToday returns how many orders are left in today's budget,
+// or -1 when MaxOrdersPerDay is zero/disabled (unlimited).
+func (s *State) RemainingOrdersToday(lim Limits) int {
+	if lim.MaxOrdersPerDay <= 0 {
+		return -1
+	}
+	if left := lim.MaxOrdersPerDay - s.OrdersToday(); left > 0 {
+		return left
+	}
+	return 0
+}
 
 // --- Volatility helpers ---
-func (s *State) PushPrice(px float64, lookback int) {
+// SetVolEWMALambda sets the decay of the EWMA vol folded in by PushPriceAt, for
+// this window and the per-symbol ones created after it.
+func (s *State) SetVolEWMALambda(lambda float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.volEWMALambda = lambda
+}
+
+func (s *State) PushPrice(px float64, lookback int) { s.PushPriceAt(time.Now(), px, lookback, 0) }
+
+// PushPriceAt records a timestamped price, keeping at most lookback entries
+// (0 = no count cap) and, when window > 0, dropping entries older than t-window.
+// It also folds the tick's return into the EWMA variance.
+func (s *State) PushPriceAt(t time.Time, px float64, lookback int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.prices); n > 0 && s.prices[n-1] != 0 {
+		r := (px - s.prices[n-1]) / s.prices[n-1]
+		if s.ewmaN == 0 {
+			s.ewmaVar = r * r
+		} else {
+			lambda := s.volEWMALambda
+			if lambda <= 0 || lambda >= 1 { lambda = DefaultEWMALambda }
+			s.ewmaVar = lambda*s.ewmaVar + (1-lambda)*r*r
+		}
+		s.ewmaN++
+	}
 	s.prices = append(s.prices, px)
-	if len(s.prices) > lookback {
-		s.prices = s.prices[1:]
+	s.priceTimes = append(s.priceTimes, t)
+	drop := 0
+	if lookback > 0 && len(s.prices) > lookback {
+		drop = len(s.prices) - lookback
+	}
+	if window > 0 {
+		cutoff := t.Add(-window)
+		for drop < len(s.priceTimes) && s.priceTimes[drop].Before(cutoff) {
+			drop++
+		}
+	}
+	s.prices = s.prices[drop:]
+	s.priceTimes = s.priceTimes[drop:]
+}
+
+// PushSymbolPriceAt is PushPriceAt for one of several symbols sharing this state;
+// each symbol keeps its own window.
+func (s *State) PushSymbolPriceAt(symbol string, t time.Time, px float64, lookback int, window time.Duration) {
+	s.mu.Lock()
+	if s.symVol == nil { s.symVol = map[string]*State{} }
+	v := s.symVol[symbol]
+	if v == nil {
+		v = &State{volEWMALambda: s.volEWMALambda}
+		s.symVol[symbol] = v
 	}
+	s.mu.Unlock()
+	v.PushPriceAt(t, px, lookback, window)
+}
+
+// symbolWindow is the symbol's own price window, if one was pushed.
+func (s *State) symbolWindow(symbol string) (*State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.symVol[symbol]
+	return v, ok
+}
+
+// RealizedVolFor is the symbol's realized vol, or RealizedVol when no prices were
+// pushed for it with PushSymbolPriceAt.
+func (s *State) RealizedVolFor(symbol string) float64 {
+	if v, ok := s.symbolWindow(symbol); ok { return v.RealizedVol() }
+	return s.RealizedVol()
+}
+
+// RealizedVolEWMAFor is RealizedVolFor for the EWMA estimator.
+func (s *State) RealizedVolEWMAFor(symbol string) float64 {
+	if v, ok := s.symbolWindow(symbol); ok { return v.RealizedVolEWMA() }
+	return s.RealizedVolEWMA()
+}
+
+// VolFor is the symbol's realized vol from the estimator method selects.
+func (s *State) VolFor(symbol string, method VolMethod) float64 {
+	if method == VolEWMA { return s.RealizedVolEWMAFor(symbol) }
+	return s.RealizedVolFor(symbol)
+}
+
+// ATRFor is the symbol's ATR, or ATR when no prices were pushed for it with
+// PushSymbolPriceAt.
+func (s *State) ATRFor(symbol string, lookback int) float64 {
+	if v, ok := s.symbolWindow(symbol); ok { return v.ATR(lookback) }
+	return s.ATR(lookback)
+}
+
+// ATR approximates the average true range from mid prices: the mean absolute
+// tick-to-tick move over the last lookback ticks of the vol window (0 = all of
+// it). Without highs and lows each tick's range is just its move.
+func (s *State) ATR(lookback int) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.prices)
+	if n < 2 {
+		return 0
+	}
+	from := 1
+	if lookback > 0 && n-lookback > from { from = n - lookback }
+	var sum float64
+	for i := from; i < n; i++ {
+		sum += math.Abs(s.prices[i] - s.prices[i-1])
+	}
+	return sum / float64(n-from)
 }
 
 // Simple realized volatility estimator
 func (s *State) RealizedVol() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	n := len(s.prices)
 	if n < 2 {
 		return 0
@@ -69,17 +514,17 @@ func (s *State) RealizedVol() float64 {
 	if len(rets) == 0 {
 		return 0
 	}
-	// mean
-	mean := 0.0
-	for _, r := range rets {
-		mean += r
-	}
-	mean /= float64(len(rets))
-	// variance
-	var varsum float64
-	for _, r := range rets {
-		d := r - mean
-		varsum += d * d
+	_, sd := util.MeanStdDev(rets)
+	return sd // standard deviation of returns
+}
+
+// RealizedVolEWMA is the exponentially weighted stdev of tick returns,
+// updated in O(1) by each push; 0 until the first return is seen.
+func (s *State) RealizedVolEWMA() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ewmaN == 0 {
+		return 0
 	}
-	return math.Sqrt(varsum / float64(len(rets))) // standard deviation of returns
+	return math.Sqrt(s.ewmaVar)
 }
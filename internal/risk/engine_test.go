@@ -0,0 +1,161 @@
+package risk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreachDailyLossZeroIsOff(t *testing.T) {
+	rs := NewState(1000, 0, time.Now())
+	rs.UpdateEquity(900)
+	if rs.BreachDailyLoss(0) {
+		t.Errorf("a 0%% limit breached on a 10%% loss; 0 must mean off")
+	}
+	if rs.CheckHalt(time.Now(), Limits{}) {
+		t.Errorf("halted with no loss limit configured")
+	}
+	if !rs.BreachDailyLoss(5) {
+		t.Errorf("a 5%% limit did not breach on a 10%% loss")
+	}
+	rs.SetInterimMaxLossPct(5) // the interim cap still applies when the daily one is off
+	if !rs.BreachDailyLoss(0) {
+		t.Errorf("interim 5%% cap did not breach on a 10%% loss")
+	}
+}
+
+func TestRemainingOrdersToday(t *testing.T) {
+	rs := NewState(1000, 0, time.Now())
+	lim := Limits{MaxOrdersPerDay: 3}
+	if n := rs.RemainingOrdersToday(lim); n != 3 {
+		t.Fatalf("remaining before any order = %d, want 3", n)
+	}
+	rs.CountOrder("BTC-USD")
+	rs.CountOrder("ETH-USD")
+	if n := rs.RemainingOrdersToday(lim); n != 1 {
+		t.Errorf("remaining after 2 of 3 = %d, want 1", n)
+	}
+	rs.CountOrder("BTC-USD")
+	rs.CountOrder("BTC-USD") // a bypassed order can overshoot the cap
+	if n := rs.RemainingOrdersToday(lim); n != 0 {
+		t.Errorf("remaining over the cap = %d, want 0", n)
+	}
+	if n := rs.RemainingOrdersToday(Limits{}); n != -1 {
+		t.Errorf("remaining with no cap = %d, want -1 (unlimited)", n)
+	}
+}
+
+func TestVolWindowPrunesByTime(t *testing.T) {
+	rs := NewState(1000, 0, time.Now())
+	t0 := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	for i, px := range []float64{100, 110, 90, 110} {
+		rs.PushPriceAt(t0.Add(time.Duration(i)*time.Second), px, 0, 5*time.Minute)
+	}
+	rs.PushPriceAt(t0.Add(3*time.Minute), 110, 0, 5*time.Minute) // still inside the window
+	if rs.RealizedVol() == 0 {
+		t.Fatal("choppy prices inside the window gave no vol")
+	}
+	// ten minutes on, the choppy ticks are older than the window and drop out
+	for i := 0; i < 3; i++ {
+		rs.PushPriceAt(t0.Add(10*time.Minute+time.Duration(i)*time.Minute), 100, 0, 5*time.Minute)
+	}
+	if v := rs.RealizedVol(); v != 0 {
+		t.Errorf("vol %v after the choppy ticks aged out, want 0", v)
+	}
+	if a := rs.ATR(0); a != 0 {
+		t.Errorf("ATR %v after the choppy ticks aged out, want 0", a)
+	}
+}
+
+func TestNetOfExitFeesLowersEquity(t *testing.T) {
+	const equity, positions, feeBps = 10000.0, 4000.0, 60.0
+	gross := NetOfExitFees(equity, positions, 0)
+	net := NetOfExitFees(equity, positions, feeBps)
+	if gross != equity {
+		t.Errorf("equity without the adjustment = %v, want %v", gross, equity)
+	}
+	if want := equity - positions*feeBps/10000; net != want {
+		t.Errorf("equity net of exit fees = %v, want %v", net, want)
+	}
+	if flat := NetOfExitFees(equity, 0, feeBps); flat != equity {
+		t.Errorf("flat account net of exit fees = %v, want %v", flat, equity)
+	}
+
+	// the conservative mark is what a tight loss limit sees
+	for _, c := range []struct {
+		eq     float64
+		breach bool
+	}{{gross, false}, {net, true}} {
+		rs := NewState(10000, 0, time.Now())
+		rs.UpdateEquity(c.eq)
+		if got := rs.BreachDailyLoss(0.2); got != c.breach {
+			t.Errorf("equity %v against a 0.2%% cap: breach=%v, want %v", c.eq, got, c.breach)
+		}
+	}
+}
+
+func TestLossCapGraceAfterOpen(t *testing.T) {
+	open := time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)
+	lim := Limits{MaxLossPctDay: 1, LossCapGrace: 5 * time.Minute, LossCapHardPct: 3}
+	for _, c := range []struct {
+		name   string
+		equity float64
+		at     time.Duration
+		halt   bool
+	}{
+		{"small loss in grace", 9850, time.Minute, false},
+		{"large loss in grace", 9600, time.Minute, true},
+		{"small loss after grace", 9850, 6 * time.Minute, true},
+	} {
+		rs := NewState(10000, 0, open)
+		rs.UpdateEquity(c.equity)
+		if got := rs.CheckHalt(open.Add(c.at), lim); got != c.halt {
+			t.Errorf("%s: halted=%v, want %v", c.name, got, c.halt)
+		}
+	}
+}
+
+func TestErrorCooldownDoublesToMaxThenResets(t *testing.T) {
+	rs := NewState(1000, 0, time.Now())
+	rs.SetErrorCooldown(10*time.Second, time.Minute)
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		rs.NoteError()
+		if got := rs.EffectiveCooldown(); got != want {
+			t.Errorf("after %d errors: cooldown %v, want %v", i+1, got, want)
+		}
+	}
+	if rs.CanAct(time.Now().Add(59 * time.Second)) {
+		t.Error("acting 59s into a 1m cooldown")
+	}
+	rs.NoteSuccess()
+	if got := rs.EffectiveCooldown(); got != 10*time.Second {
+		t.Errorf("after a success: cooldown %v, want the 10s base", got)
+	}
+}
+
+// A single shock moves the EWMA estimate more than the equal-weighted one, and
+// it also fades from the EWMA sooner once the market calms down.
+func TestEWMAVolReactsFasterThanSimple(t *testing.T) {
+	rs := NewState(1000, 0, time.Now())
+	px := 100.0
+	push := func(n int) {
+		for i := 0; i < n; i++ {
+			if i%2 == 0 { px *= 1.001 } else { px /= 1.001 }
+			rs.PushPrice(px, 50)
+		}
+	}
+	push(50)
+	simpleCalm, ewmaCalm := rs.RealizedVol(), rs.RealizedVolEWMA()
+
+	px *= 1.05
+	rs.PushPrice(px, 50)
+	simpleSpike, ewmaSpike := rs.RealizedVol(), rs.RealizedVolEWMA()
+	if ewmaSpike/ewmaCalm <= simpleSpike/simpleCalm {
+		t.Errorf("spike: EWMA rose %.1fx, simple %.1fx; want the EWMA to react more",
+			ewmaSpike/ewmaCalm, simpleSpike/simpleCalm)
+	}
+
+	push(30) // the spike is still inside the 50-tick simple window
+	if simple, ewma := rs.RealizedVol(), rs.RealizedVolEWMA(); ewma >= simple {
+		t.Errorf("30 calm ticks on: EWMA %.5f, simple %.5f; want the EWMA to have decayed below", ewma, simple)
+	}
+}
@@ -0,0 +1,127 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// entry is the open quantity and weighted-average entry price for one symbol.
+type entry struct {
+	qty, avg float64
+	first    float64 // price of the fill that opened the position
+	adds     int     // buys on top of the opening one (scale-ins)
+}
+
+// NoteFill updates the symbol's weighted-average entry price. Buys average in;
+// sells reduce quantity at the same average, and a full close forgets the entry.
+func (s *State) NoteFill(symbol string, buy bool, qty, price float64) {
+	if qty <= 0 || price <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]entry{}
+	}
+	e := s.entries[symbol]
+	if buy {
+		if e.qty > 0 {
+			e.adds++
+		} else {
+			e.first = price
+		}
+		e.avg = (e.avg*e.qty + price*qty) / (e.qty + qty)
+		e.qty += qty
+		s.entries[symbol] = e
+		return
+	}
+	if e.qty -= qty; e.qty <= 1e-12 {
+		delete(s.entries, symbol)
+		return
+	}
+	s.entries[symbol] = e
+}
+
+// EntryPrice is the symbol's weighted-average entry (0 = no position).
+func (s *State) EntryPrice(symbol string) float64 {
+	e, _ := s.openEntry(symbol)
+	return e.avg
+}
+
+// openEntry returns a copy of the symbol's entry and whether one is open.
+func (s *State) openEntry(symbol string) (entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[symbol]
+	return e, ok
+}
+
+// Entries returns each open symbol's average entry price (e.g. for a snapshot).
+func (s *State) Entries() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]float64, len(s.entries))
+	for k, e := range s.entries { out[k] = e.avg }
+	return out
+}
+
+// RestoreEntries seeds average entry prices for symbols with no entry yet. The
+// quantity is not part of the snapshot, so the next buy (e.g. replaying the
+// ledger position) re-weights from its own fill.
+func (s *State) RestoreEntries(avg map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]entry{}
+	}
+	for k, v := range avg {
+		if _, ok := s.entries[k]; !ok && v > 0 {
+			s.entries[k] = entry{avg: v, first: v}
+		}
+	}
+}
+
+// ResetEntries forgets every open entry and round trip, e.g. when the positions they describe
+// no longer exist (paper warm-up promoted to a live account).
+func (s *State) ResetEntries() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+	s.tripPnL = nil
+}
+
+// ScaleIns is how many buys were added to the symbol's open position since it
+// was opened; a full close resets it.
+func (s *State) ScaleIns(symbol string) int {
+	e, _ := s.openEntry(symbol)
+	return e.adds
+}
+
+// capScaleIn denies an allowed buy that would add to an open position beyond
+// lim.MaxScaleIns, or move its average entry more than lim.MaxAvgEntryDriftPct
+// away from the opening fill, so a repeated signal can't keep averaging down.
+func capScaleIn(rs *State, lim Limits, symbol string, price float64, dec Decision) Decision {
+	e, open := rs.openEntry(symbol)
+	if !dec.Allow || !open || e.qty <= 0 {
+		return dec
+	}
+	if lim.MaxScaleIns > 0 && e.adds >= lim.MaxScaleIns {
+		return deny(DenyScaleIn, fmt.Sprintf("%d scale-ins already (max %d)", e.adds, lim.MaxScaleIns))
+	}
+	if lim.MaxAvgEntryDriftPct > 0 && e.first > 0 {
+		avg := (e.avg*e.qty + price*dec.Qty) / (e.qty + dec.Qty)
+		if drift := math.Abs(avg-e.first) / e.first * 100; drift > lim.MaxAvgEntryDriftPct {
+			return deny(DenyScaleIn, fmt.Sprintf("avg entry would drift %.2f%% from %.2f (max %.2f%%)", drift, e.first, lim.MaxAvgEntryDriftPct))
+		}
+	}
+	return dec
+}
+
+// TakeProfitTriggered reports whether price is at least lim.TakeProfitPct above
+// entryPrice. It is false with no entry (entryPrice 0) or when the target is off.
+func (s *State) TakeProfitTriggered(lim Limits, entryPrice, price float64) bool {
+	if lim.TakeProfitPct <= 0 || entryPrice <= 0 {
+		return false
+	}
+	return (price-entryPrice)/entryPrice*100 >= lim.TakeProfitPct
+}
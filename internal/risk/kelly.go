@@ -0,0 +1,74 @@
+package risk
+
+import "math"
+
+// NoteTradeOutcome records the realized PnL of a completed (closing) trade for
+// Kelly sizing, keeping the latest lookback outcomes (0 = 50). A loss extends
+// the losing streak and a win ends it; a flat trade leaves it as it is.
+func (s *State) NoteTradeOutcome(pnlUSD float64, lookback int) {
+	if lookback <= 0 { lookback = 50 }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case pnlUSD < 0:
+		s.lossStreak++
+	case pnlUSD > 0:
+		s.lossStreak = 0
+	}
+	s.outcomes = append(s.outcomes, pnlUSD)
+	if len(s.outcomes) > lookback {
+		s.outcomes = s.outcomes[len(s.outcomes)-lookback:]
+	}
+}
+
+// NoteSellPnL adds one sell's realized PnL to the symbol's open round trip.
+// When closed (the sell left the position flat) the round trip's total goes to
+// NoteTradeOutcome, so a position exited in several sells is one outcome.
+func (s *State) NoteSellPnL(symbol string, pnlUSD float64, closed bool, lookback int) {
+	s.mu.Lock()
+	if s.tripPnL == nil { s.tripPnL = map[string]float64{} }
+	total := s.tripPnL[symbol] + pnlUSD
+	if !closed {
+		s.tripPnL[symbol] = total
+		s.mu.Unlock()
+		return
+	}
+	delete(s.tripPnL, symbol)
+	s.mu.Unlock()
+	s.NoteTradeOutcome(total, lookback)
+}
+
+// KellyEstimate estimates the Kelly-optimal fraction of equity to risk,
+// f* = W - (1-W)/R, from the recorded outcomes: W is the win rate and R the
+// average win over the average loss. f is clamped to [0, 1]. ok is false while
+// fewer than minTrades (0 = 10) outcomes are recorded; n is how many there are.
+func (s *State) KellyEstimate(minTrades int) (f, winRate, payoff float64, n int, ok bool) {
+	if minTrades <= 0 { minTrades = 10 }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n = len(s.outcomes)
+	if n < minTrades {
+		return 0, 0, 0, n, false
+	}
+	var wins, sumWin, sumLoss float64
+	for _, pnl := range s.outcomes {
+		if pnl > 0 {
+			wins++
+			sumWin += pnl
+		} else {
+			sumLoss -= pnl
+		}
+	}
+	winRate = wins / float64(n)
+	switch {
+	case wins == 0:
+		return 0, 0, 0, n, true
+	case sumLoss == 0:
+		payoff = math.Inf(1) // nothing lost yet: f* is just the win rate
+		f = winRate
+	default:
+		payoff = (sumWin / wins) / (sumLoss / (float64(n) - wins))
+		f = winRate - (1-winRate)/payoff
+	}
+	return math.Max(0, math.Min(1, f)), winRate, payoff, n, true
+}
@@ -0,0 +1,40 @@
+package risk
+
+import "testing"
+
+// A losing position sold off in three slices is one losing trade.
+func TestPartialSellsAreOneLosingTrade(t *testing.T) {
+	rs := newTestState()
+	rs.NoteSellPnL("BTC-USD", 5, true, 50) // an earlier winner
+	for i, closed := range []bool{false, false, true} {
+		rs.NoteSellPnL("BTC-USD", -10, closed, 50)
+		want := 0
+		if closed { want = 1 }
+		if got := rs.LossStreak(); got != want {
+			t.Fatalf("after sell %d: loss streak %d, want %d", i+1, got, want)
+		}
+	}
+	// a slice that realizes a gain doesn't rescue a round trip that lost overall
+	rs.NoteSellPnL("ETH-USD", 4, false, 50)
+	rs.NoteSellPnL("ETH-USD", -9, true, 50)
+	if got := rs.LossStreak(); got != 2 {
+		t.Errorf("loss streak %d after a net-losing round trip, want 2", got)
+	}
+}
+
+// Kelly samples completed round trips, not the tranches that close them.
+func TestTrancheExitIsOneKellySample(t *testing.T) {
+	rs := newTestState()
+	rs.NoteSellPnL("BTC-USD", -20, true, 50)
+	for _, pnl := range []float64{3, 6, 9} { // a ladder taking three profitable tranches
+		rs.NoteSellPnL("BTC-USD", pnl, false, 50)
+		if _, _, _, n, _ := rs.KellyEstimate(1); n != 1 {
+			t.Fatalf("open round trip counted: %d samples, want 1", n)
+		}
+	}
+	rs.NoteSellPnL("BTC-USD", 2, true, 50)
+	_, win, payoff, n, _ := rs.KellyEstimate(1)
+	if n != 2 || win != 0.5 || payoff != 1 {
+		t.Errorf("samples %d, win rate %v, payoff %v; want 2, 0.5, 1 (one 20 win, one 20 loss)", n, win, payoff)
+	}
+}
@@ -0,0 +1,65 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TPLevel is one rung of a take-profit ladder: once the unrealized gain reaches
+// GainPct, close ClosePct of the position's peak size (100 = close the rest).
+type TPLevel struct {
+	GainPct  float64
+	ClosePct float64
+}
+
+// ParseTPLadder parses "1:50,2:30,3:100" (gain%:close%) into levels sorted by gain.
+func ParseTPLadder(s string) ([]TPLevel, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var out []TPLevel
+	for _, part := range strings.Split(s, ",") {
+		gs, cs, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("tp ladder: %q is not gain:close", part)
+		}
+		g, err := strconv.ParseFloat(strings.TrimSpace(gs), 64)
+		if err != nil || g <= 0 {
+			return nil, fmt.Errorf("tp ladder: bad gain %q", gs)
+		}
+		c, err := strconv.ParseFloat(strings.TrimSpace(cs), 64)
+		if err != nil || c <= 0 || c > 100 {
+			return nil, fmt.Errorf("tp ladder: bad close pct %q", cs)
+		}
+		out = append(out, TPLevel{GainPct: g, ClosePct: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GainPct < out[j].GainPct })
+	return out, nil
+}
+
+// NextTPTranche returns the quantity to close when price has reached the next
+// unfilled ladder level. `filled` is the number of levels already taken, sold
+// what was already sold toward the next one, peakQty the largest size held
+// since entry and qty what is still open. The last level always closes
+// whatever remains.
+func NextTPTranche(ladder []TPLevel, filled int, sold, avgEntry, price, peakQty, qty float64) (float64, bool) {
+	if filled >= len(ladder) || avgEntry <= 0 || qty <= 0 {
+		return 0, false
+	}
+	lvl := ladder[filled]
+	gainPct := (price - avgEntry) / avgEntry * 100
+	if gainPct < lvl.GainPct {
+		return 0, false
+	}
+	if filled == len(ladder)-1 {
+		return qty, true
+	}
+	tranche := peakQty*lvl.ClosePct/100 - sold
+	if tranche > qty {
+		tranche = qty
+	}
+	return tranche, tranche > 0
+}
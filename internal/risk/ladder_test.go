@@ -0,0 +1,57 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNextTPTrancheSubtractsPartialSale(t *testing.T) {
+	ladder := []TPLevel{{GainPct: 1, ClosePct: 50}, {GainPct: 2, ClosePct: 100}}
+	// first level: half of the 10 peak
+	if q, ok := NextTPTranche(ladder, 0, 0, 100, 101, 10, 10); !ok || q != 5 {
+		t.Fatalf("tranche = %v, %v; want 5", q, ok)
+	}
+	// 3 of it went out in a capped order: only the other 2 are left for the level
+	if q, ok := NextTPTranche(ladder, 0, 3, 100, 101, 10, 7); !ok || q != 2 {
+		t.Fatalf("rest of tranche = %v, %v; want 2", q, ok)
+	}
+	// below the level's gain nothing is due
+	if _, ok := NextTPTranche(ladder, 0, 3, 100, 100.5, 10, 7); ok {
+		t.Fatalf("tranche due below the level's gain")
+	}
+}
+
+func TestTPLadderWalksUpThroughLevels(t *testing.T) {
+	ladder, err := ParseTPLadder("1:30,2:30,3:100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, qty := 0, 10.0
+	steps := []struct {
+		price float64
+		want  float64 // quantity closed at this price, 0 = none
+	}{
+		{100.5, 0},
+		{101, 3},   // level 1: 30% of the 10 peak
+		{101.5, 0}, // level 1 taken, level 2 not reached
+		{102, 3},   // level 2: another 30% of the peak, not of what's left
+		{102.5, 0},
+		{103, 4}, // last level closes the rest
+	}
+	for _, s := range steps {
+		q, ok := NextTPTranche(ladder, filled, 0, 100, s.price, 10, qty)
+		if !ok {
+			q = 0
+		}
+		if math.Abs(q-s.want) > 1e-9 {
+			t.Fatalf("at %v closed %v, want %v", s.price, q, s.want)
+		}
+		if ok {
+			filled++
+			qty -= q
+		}
+	}
+	if filled != 3 || math.Abs(qty) > 1e-9 {
+		t.Errorf("after the walk: %d levels filled, %v left; want 3 and 0", filled, qty)
+	}
+}
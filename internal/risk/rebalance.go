@@ -0,0 +1,29 @@
+package risk
+
+// Rebalance sizes the order that moves the position toward target, a fraction of
+// the symbol's MaxPositionUSD (spot: negative targets mean flat). It returns
+// ok=false when the gap is within bandUSD. The order is still bounded by
+// DecideBuy/DecideSell caps; a rebalancing sell is discretionary, so unlike a
+// protective exit it also counts against the daily order caps.
+func Rebalance(rs *State, lim Limits, symbol string, price, posUSD, posQty, avgEntry, target, bandUSD float64) (buy bool, dec Decision, ok bool) {
+	if target < 0 { target = 0 }
+	if target > 1 { target = 1 }
+	delta := target*lim.ForSymbol(symbol).MaxPositionUSD - posUSD
+	if delta == 0 || (delta > 0 && delta <= bandUSD) || (delta < 0 && -delta <= bandUSD) {
+		return false, Decision{}, false
+	}
+	if delta > 0 {
+		dec = DecideBuy(rs, lim, symbol, price, posUSD, avgEntry)
+		if dec.Allow && dec.NotionalUSD > delta {
+			dec.NotionalUSD, dec.Qty = delta, delta/price
+			dec = minTrade(dec, lim)
+		}
+		return true, dec, true
+	}
+	if d, capped := orderCap(rs, lim, lim.ForSymbol(symbol), symbol); capped {
+		return false, d, true
+	}
+	qty := -delta / price
+	if qty > posQty { qty = posQty }
+	return false, DecideSell(rs, lim, symbol, price, qty, avgEntry), true
+}
@@ -0,0 +1,36 @@
+package risk
+
+import "testing"
+
+func TestRebalanceBand(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{MaxPositionUSD: 1000, MaxOrderNotionalUSD: 1000, MinTradeUSD: 10, MaxOrdersPerDay: 1}
+	const price, target, band = 100.0, 0.5, 50.0 // aim for 500 USD, leave it alone within 450..550
+	for _, posUSD := range []float64{460, 500, 540} {
+		if _, dec, ok := Rebalance(rs, lim, "BTC-USD", price, posUSD, posUSD/price, 100, target, band); ok {
+			t.Errorf("at %v USD: rebalanced inside the band: %+v", posUSD, dec)
+		}
+	}
+	// below the band: buy only the gap, though the caps would allow more
+	buy, dec, ok := Rebalance(rs, lim, "BTC-USD", price, 300, 3, 100, target, band)
+	if !ok || !buy || !dec.Allow || dec.NotionalUSD != 200 || dec.Qty != 2 {
+		t.Errorf("at 300 USD: buy=%v ok=%v %+v, want a 200 USD buy", buy, ok, dec)
+	}
+	// above the band: sell the excess
+	buy, dec, ok = Rebalance(rs, lim, "BTC-USD", price, 700, 7, 100, target, band)
+	if !ok || buy || !dec.Allow || dec.Qty != 2 {
+		t.Errorf("at 700 USD: buy=%v ok=%v %+v, want a sell of 2", buy, ok, dec)
+	}
+	// a gap cut below MinTradeUSD is dust
+	if _, dec, _ := Rebalance(rs, lim, "BTC-USD", price, 495, 4.95, 100, target, 0); dec.Allow || dec.Code != DenyMinTrade {
+		t.Errorf("5 USD top-up = %+v, want denied with %s", dec, DenyMinTrade)
+	}
+	// once the day's orders are used up, neither side trades
+	rs.CountOrder("BTC-USD")
+	if _, dec, _ := Rebalance(rs, lim, "BTC-USD", price, 700, 7, 100, target, band); dec.Allow || dec.Code != DenyOrderCap {
+		t.Errorf("sell past the order cap = %+v, want denied with %s", dec, DenyOrderCap)
+	}
+	if _, dec, _ := Rebalance(rs, lim, "BTC-USD", price, 300, 3, 100, target, band); dec.Allow || dec.Code != DenyOrderCap {
+		t.Errorf("buy past the order cap = %+v, want denied with %s", dec, DenyOrderCap)
+	}
+}
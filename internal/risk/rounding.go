@@ -0,0 +1,90 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundQty applies the symbol's venue constraints to an allowed decision: sizes
+// are floored to whole units when the instrument requires integer quantities and
+// to the lot step when one is set, and a size that rounds to zero or below the
+// venue minimum is denied rather than sent as a reject.
+func RoundQty(dec Decision, lim Limits, symbol string, price float64) Decision {
+	if !dec.Allow {
+		return dec
+	}
+	inst := lim.Instruments[symbol]
+	qty := dec.Qty
+	if inst.IntegerQty {
+		qty = math.Floor(qty + 1e-9)
+	}
+	if inst.StepSize > 0 {
+		steps := math.Floor(qty/inst.StepSize + 1e-9)
+		// re-derive from the step's own precision so 0.1234 doesn't come back as 0.12339999
+		qty, _ = strconv.ParseFloat(strconv.FormatFloat(steps*inst.StepSize, 'f', stepDecimals(inst.StepSize), 64), 64)
+	}
+	if qty <= 0 {
+		return deny(DenyLotSize, "size rounds to zero")
+	}
+	if inst.MinQty > 0 && qty < inst.MinQty {
+		return deny(DenyLotSize, fmt.Sprintf("size %g below minimum lot %g", qty, inst.MinQty))
+	}
+	dec.Qty, dec.NotionalUSD = qty, qty*price
+	return dec
+}
+
+// stepDecimals is the number of decimal places in step (0.00000001 -> 8).
+func stepDecimals(step float64) int {
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// QtyPrecision is how many decimals the instrument's quantities carry: the lot
+// step's, 0 for whole units, else 8.
+func (i Instrument) QtyPrecision() int {
+	switch {
+	case i.StepSize > 0:
+		return stepDecimals(i.StepSize)
+	case i.IntegerQty:
+		return 0
+	}
+	return 8
+}
+
+// PricePrecision is how many decimals the instrument's prices carry: the price
+// tick's, else 2.
+func (i Instrument) PricePrecision() int {
+	if i.PriceTick > 0 { return stepDecimals(i.PriceTick) }
+	return 2
+}
+
+// FormatQty renders qty at the instrument's quantity precision.
+func (i Instrument) FormatQty(qty float64) string {
+	return strconv.FormatFloat(qty, 'f', i.QtyPrecision(), 64)
+}
+
+// FormatPrice renders price at the instrument's price precision.
+func (i Instrument) FormatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', i.PricePrecision(), 64)
+}
+
+// RoundPrice snaps a limit price to the price tick, toward the passive side: a
+// buy is floored and a sell raised, so rounding never makes the order cross.
+func (i Instrument) RoundPrice(price float64, buy bool) float64 {
+	if i.PriceTick <= 0 || price <= 0 {
+		return price
+	}
+	ticks := price / i.PriceTick
+	if buy {
+		ticks = math.Floor(ticks + 1e-9)
+	} else {
+		ticks = math.Ceil(ticks - 1e-9)
+	}
+	out, _ := strconv.ParseFloat(strconv.FormatFloat(ticks*i.PriceTick, 'f', i.PricePrecision(), 64), 64)
+	return out
+}
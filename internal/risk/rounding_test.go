@@ -0,0 +1,47 @@
+package risk
+
+import "testing"
+
+func TestRoundQtyToStep(t *testing.T) {
+	lim := Limits{Instruments: map[string]Instrument{}}
+	cases := []struct {
+		step, want float64
+	}{
+		{0.0001, 0.1234},
+		{0.00000001, 0.12345678},
+		{0.01, 0.12},
+	}
+	for _, c := range cases {
+		lim.Instruments["BTC-USD"] = Instrument{StepSize: c.step, MinQty: 0.001}
+		dec := RoundQty(Decision{Allow: true, Qty: 0.123456789}, lim, "BTC-USD", 100)
+		if !dec.Allow || dec.Qty != c.want {
+			t.Errorf("step %g: %+v, want qty %v", c.step, dec, c.want)
+		}
+		if dec.NotionalUSD != c.want*100 {
+			t.Errorf("step %g: notional %v, want %v", c.step, dec.NotionalUSD, c.want*100)
+		}
+	}
+	lim.Instruments["BTC-USD"] = Instrument{StepSize: 0.0001, MinQty: 0.2}
+	if dec := RoundQty(Decision{Allow: true, Qty: 0.123456789}, lim, "BTC-USD", 100); dec.Allow || dec.Code != DenyLotSize {
+		t.Errorf("below MinQty: %+v, want denied with %s", dec, DenyLotSize)
+	}
+}
+
+func TestDecideSellRoundsToStep(t *testing.T) {
+	rs := newTestState()
+	lim := Limits{Instruments: map[string]Instrument{"BTC-USD": {StepSize: 0.0001}}}
+	if dec := DecideSell(rs, lim, "BTC-USD", 100, 0.123456789, 100); !dec.Allow || dec.Qty != 0.1234 {
+		t.Errorf("sell = %+v, want 0.1234", dec)
+	}
+}
+
+func TestRoundQtyToWholeContracts(t *testing.T) {
+	lim := Limits{Instruments: map[string]Instrument{"BTC-PERP": {IntegerQty: true}}}
+	dec := RoundQty(Decision{Allow: true, Qty: 3.97, NotionalUSD: 397}, lim, "BTC-PERP", 100)
+	if !dec.Allow || dec.Qty != 3 || dec.NotionalUSD != 300 {
+		t.Errorf("3.97 contracts = %+v, want 3 for 300 USD", dec)
+	}
+	if dec := RoundQty(Decision{Allow: true, Qty: 0.8}, lim, "BTC-PERP", 100); dec.Allow || dec.Code != DenyLotSize {
+		t.Errorf("0.8 contracts = %+v, want denied with %s", dec, DenyLotSize)
+	}
+}
@@ -0,0 +1,73 @@
+package risk
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStateConcurrentSymbols drives one State from several goroutines per
+// symbol (price feed, decisions, snapshot writer), the way the bot does; run
+// it with -race.
+func TestStateConcurrentSymbols(t *testing.T) {
+	rs := NewState(10000, 0, time.Now())
+	rs.UpdateEquity(10000)
+	lim := Limits{
+		MaxPositionUSD: 1000, MaxOrderNotionalUSD: 100, MaxOrdersPerDay: 1000,
+		MaxLossPctDay: 50, MaxConsecutiveLosses: 1000, TrailingStopPct: 1, MaxScaleIns: 5,
+	}
+	var wg sync.WaitGroup
+	run := func(f func(j int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ { f(j) }
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		sym := fmt.Sprintf("SYM%d-USD", i)
+		run(func(j int) { // price feed: peaks and fills
+			px := 100 + float64(j%10)
+			if rs.TrailingStopTriggered(lim, sym, px) {
+				rs.ResetPeak(sym)
+			}
+			rs.NoteFill(sym, j%3 != 2, 0.1, px)
+		})
+		run(func(j int) { // decisions and closed trades
+			px := 100 + float64(j%10)
+			DecideBuy(rs, lim, sym, px, 0, rs.EntryPrice(sym))
+			rs.NoteTradeOutcome(float64(j%5-2), 50)
+			rs.KellyEstimate(10)
+			rs.UpdateEquity(10000 - float64(j))
+		})
+	}
+	run(func(int) { // day snapshot and /status
+		_, _, _ = rs.Peaks(), rs.Entries(), rs.ScaleIns("SYM0-USD")
+		_, _, _ = rs.Halted(), rs.HaltCause(), rs.EquityAtOpen()
+		rs.SetInterimMaxLossPct(rs.InterimMaxLossPct())
+		_, _ = rs.WeekOpen()
+		_, _ = rs.MonthOpen()
+		_ = rs.DayOpen()
+	})
+	run(func(j int) { // day manager: period anchors; order path: errors
+		open := time.Now()
+		rs.SetWeekOpen(open, 10000)
+		rs.SetMonthOpen(open, 10000)
+		rs.PeriodLossBreach(Limits{MaxLossPctWeek: 50, MaxLossPctMonth: 50})
+		rs.BreachDailyLossAt(open, Limits{MaxLossPctDay: 50, LossCapGrace: time.Minute})
+		if j%2 == 0 { rs.NoteError() } else { rs.NoteSuccess() }
+		rs.CanAct(open)
+	})
+	run(func(j int) { // config reload and the price feed's EWMA
+		rs.SetErrorCooldown(0, time.Duration(j)*time.Millisecond)
+		rs.EffectiveCooldown()
+		rs.SetVolEWMALambda(0.9)
+		rs.PushSymbolPriceAt("SYM0-USD", time.Now(), 100+float64(j%3), 50, 0)
+		rs.PushPriceAt(time.Now(), 100+float64(j%3), 50, 0)
+	})
+	wg.Wait()
+	if rs.Halted() {
+		t.Errorf("halted (%s) with losses inside every limit", rs.HaltCause())
+	}
+}
@@ -0,0 +1,57 @@
+package risk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SymbolLimits overrides the global caps for one symbol. Zero fields fall back to
+// the corresponding global value in Limits.
+type SymbolLimits struct {
+	MaxPositionUSD  float64
+	MaxOrdersPerDay int
+}
+
+// ForSymbol returns the effective caps for symbol: its PerSymbol entry where set,
+// the global values otherwise.
+func (l Limits) ForSymbol(symbol string) SymbolLimits {
+	eff := SymbolLimits{MaxPositionUSD: l.MaxPositionUSD, MaxOrdersPerDay: l.MaxOrdersPerDay}
+	if o, ok := l.PerSymbol[symbol]; ok {
+		if o.MaxPositionUSD > 0 { eff.MaxPositionUSD = o.MaxPositionUSD }
+		if o.MaxOrdersPerDay > 0 { eff.MaxOrdersPerDay = o.MaxOrdersPerDay }
+	}
+	return eff
+}
+
+// ParseSymbolLimits parses "symbol:max_position_usd:max_orders,..." (e.g.
+// "BTC-USD:500:20,ETH-USD:200:10"); a 0 field keeps the global value.
+func ParseSymbolLimits(spec string) (map[string]SymbolLimits, error) {
+	out := map[string]SymbolLimits{}
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		f := strings.Split(part, ":")
+		if len(f) != 3 || f[0] == "" {
+			return nil, fmt.Errorf("per-symbol limits: %q is not symbol:max_position_usd:max_orders", part)
+		}
+		pos, err := strconv.ParseFloat(f[1], 64)
+		if err != nil || pos < 0 {
+			return nil, fmt.Errorf("per-symbol limits: bad max position %q", f[1])
+		}
+		orders, err := strconv.Atoi(f[2])
+		if err != nil || orders < 0 {
+			return nil, fmt.Errorf("per-symbol limits: bad order count %q", f[2])
+		}
+		out[f[0]] = SymbolLimits{MaxPositionUSD: pos, MaxOrdersPerDay: orders}
+	}
+	return out, nil
+}
+
+// OrdersTodayFor is the number of orders counted for symbol today.
+func (s *State) OrdersTodayFor(symbol string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ordersBySymbol[symbol]
+}
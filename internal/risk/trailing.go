@@ -0,0 +1,65 @@
+package risk
+
+// TrailingStopTriggered records price as the symbol's high-water mark if it is a
+// new peak, then reports whether price has fallen the stop distance below that
+// peak. Call it only while a position is open, and ResetPeak once it is closed,
+// so the peak always dates from the current entry.
+//
+// The distance is lim.TrailingStopATR times the symbol's ATR when that is set
+// and an ATR is available; otherwise it is lim.TrailingStopPct of the peak. So
+// with both configured, ATR wins and the percent only covers the warm-up.
+func (s *State) TrailingStopTriggered(lim Limits, symbol string, price float64) bool {
+	if (lim.TrailingStopPct <= 0 && lim.TrailingStopATR <= 0) || price <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	if s.peaks == nil {
+		s.peaks = map[string]float64{}
+	}
+	peak := s.peaks[symbol]
+	if price > peak {
+		s.peaks[symbol] = price
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+	if lim.TrailingStopATR > 0 {
+		if atr := s.ATRFor(symbol, lim.ATRLookback); atr > 0 {
+			return price <= peak-lim.TrailingStopATR*atr
+		}
+	}
+	return lim.TrailingStopPct > 0 && price <= peak*(1-lim.TrailingStopPct/100)
+}
+
+// Peak returns the recorded high-water price for symbol (0 = none).
+func (s *State) Peak(symbol string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peaks[symbol]
+}
+
+// ResetPeak forgets the symbol's high-water mark (position closed).
+func (s *State) ResetPeak(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.peaks, symbol)
+}
+
+// Peaks returns a copy of the recorded high-water prices (e.g. for a snapshot).
+func (s *State) Peaks() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]float64, len(s.peaks))
+	for k, v := range s.peaks { out[k] = v }
+	return out
+}
+
+// RestorePeaks replaces the high-water prices (e.g. from a snapshot).
+func (s *State) RestorePeaks(peaks map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peaks = make(map[string]float64, len(peaks))
+	for k, v := range peaks {
+		if v > 0 { s.peaks[k] = v }
+	}
+}
@@ -1,37 +1,170 @@
 package risk
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Limits defines static configuration for risk controls.
 type Limits struct {
 	MaxPositionUSD       float64 // maximum exposure in USD
 	MaxOrderNotionalUSD  float64 // max USD size per single order
 	MaxOrdersPerDay      int     // order count cap per day
+	MinTradeUSD          float64 // smallest notional worth sending (venue minimum), 0 = off
 	MaxLossPctDay        float64 // daily kill-switch loss threshold (%)
+	LossCapGrace         time.Duration // after day open, only LossCapHardPct can trip the kill-switch
+	LossCapHardPct       float64 // loss (%) that trips even inside the grace window (0 = none)
+	MaxLossPctWeek       float64 // loss (%) since the ISO week open that halts trading, 0 = off
+	MaxLossPctMonth      float64 // loss (%) since the calendar month open that halts trading, 0 = off
+	MaxDrawdownPct       float64 // drop (%) from today's peak equity that stops new buys (sells and exits still run; not a halt), 0 = off
+	MaxConsecutiveLosses int     // losing closed trades in a row that halt trading until the next day, 0 = off
 
 	VolSizingOn          bool    // enable volatility-aware sizing
 	VolLookback          int     // number of ticks for realized vol
+	VolWindow            time.Duration // wall-clock realized-vol window (0 = tick count only)
+	VolMethod            VolMethod     // estimator vol sizing uses ("" = simple)
+	VolEWMALambda        float64       // EWMA decay per tick, in (0,1); higher = slower (0 = 0.94)
 	TargetRiskBp         float64 // target basis points risk per trade (e.g., 50 = 0.50%)
+
+	SizingMode           SizingMode // how orders are sized ("" = fixed USD)
+	FixedBaseQty         float64    // base quantity per order when SizingMode is base
+	FixedNotionalUSD     float64    // USD per buy, clamped by the caps (0 = off; vol sizing takes precedence)
+	KellyFraction        float64    // share of the Kelly-optimal size to use when SizingMode is kelly (0 = full Kelly)
+	KellyLookback        int        // completed trades kept for the Kelly estimate (0 = 50)
+	KellyMinTrades       int        // completed trades needed before Kelly sizing applies (0 = 10)
+
+	SellMode             SellMode  // how much of the position a strategy sell signal exits ("" = full)
+	SellFraction         float64   // share of the position sold per signal when SellMode is fraction (0..1]
+	SellNotionalUSD      float64   // USD sold per signal when SellMode is notional
+
+	TPLadder             []TPLevel // take-profit ladder (partial closes), empty = off
+	TrailingStopPct      float64   // exit when price falls this % below its peak since entry, 0 = off
+	TrailingStopATR      float64   // trailing stop distance as a multiple of ATR; overrides TrailingStopPct once ATR is known, 0 = off
+	ATRLookback          int       // tick ranges averaged into ATR (0 = the whole vol window)
+	TakeProfitPct        float64   // exit the whole position at this % gain over avg entry, 0 = off
+
+	MaxConcentrationPct  float64 // max share of portfolio value in one symbol (%), 0 = off
+	MaxSpreadBps         float64 // deny strategy trades while the bid/ask spread is wider (bps of mid), 0 = off
+	MaxScaleIns          int     // buys allowed on top of an open position until it is closed, 0 = unlimited
+	MaxAvgEntryDriftPct  float64 // deny an add that moves avg entry this % away from the first fill, 0 = off
+
+	Instruments          map[string]Instrument // per-symbol venue constraints
+	PerSymbol            map[string]SymbolLimits // per-symbol overrides of the global caps
+}
+
+// Instrument holds venue order constraints for one symbol.
+type Instrument struct {
+	IntegerQty bool    // quantities must be whole units (e.g. futures contracts)
+	StepSize   float64 // quantities are floored to a multiple of this (0 = any)
+	MinQty     float64 // smallest quantity the venue accepts (0 = no minimum)
+	PriceTick  float64 // price increment; limit prices are rounded to it (0 = any)
 }
 
-// State tracks dynamic trading state and rolling metrics.
+// SizingMode selects how DecideBuy/DecideSell size orders.
+type SizingMode string
+
+const (
+	SizingFixed SizingMode = "fixed" // size from the USD caps (default)
+	SizingBase  SizingMode = "base"  // fixed base quantity per order, still clamped by the USD caps
+	SizingVol   SizingMode = "vol"   // volatility targeting (same as VolSizingOn)
+	SizingKelly SizingMode = "kelly" // fraction of the Kelly-optimal size from recent trade outcomes
+)
+
+// SellMode selects how much a strategy sell signal exits; protective exits
+// (stops, take-profit) always close what they were asked to.
+type SellMode string
+
+const (
+	SellFull     SellMode = "full"     // the whole position (default)
+	SellFraction SellMode = "fraction" // SellFraction of the position
+	SellNotional SellMode = "notional" // SellNotionalUSD worth of the position
+)
+
+// VolMethod selects the realized-vol estimator used for vol sizing.
+type VolMethod string
+
+const (
+	VolSimple VolMethod = "simple" // equal-weighted stdev of returns over the vol window (default)
+	VolEWMA   VolMethod = "ewma"   // exponentially weighted, reacts faster to regime changes
+)
+
+// DefaultEWMALambda is the RiskMetrics decay, used when none is configured.
+const DefaultEWMALambda = 0.94
+
+// State tracks dynamic trading state and rolling metrics. Every field is behind
+// mu and only reached through methods, so State can be shared across goroutines
+// (the trading loop, the order path, the day manager and /status).
 type State struct {
-	EquityAtOpenUSD   float64   // starting equity at day open
-	EquityNowUSD      float64   // updated equity
-	OrdersToday       int       // count of orders sent
-	RealizedPnLUSD    float64   // realized PnL tracker
+	mu                sync.Mutex // guards every unexported field below
+	equityAtOpenUSD   float64   // starting equity at day open
+	equityNowUSD      float64   // updated equity
+	peakEquityUSD     float64   // high-water equity since day open
+	ordersToday       int       // count of orders sent
+	ordersBySymbol    map[string]int // per-symbol share of ordersToday
+	realizedPnLUSD    float64   // realized PnL tracker
+
+	interimMaxLossPct float64   // tighter loss cap for the rest of the day (0 = off)
+	halted            bool      // daily loss limit hit: no orders until the next day rollover
+	haltCause         DenialReason // what set halted: DenyDailyLoss or DenyLossStreak ("" = daily loss)
+	lossStreak        int       // losing closed trades since the last winning one
+
+	weekOpen          time.Time // ISO week anchor (not reset by ResetDay)
+	equityAtWeekOpen  float64
+	monthOpen         time.Time // calendar month anchor (not reset by ResetDay)
+	equityAtMonthOpen float64
+
+	lastErrorTime     time.Time // for cooldowns
+	errorCooldown     time.Duration
+	maxErrorCooldown  time.Duration // cap for the doubling cooldown (<= errorCooldown = fixed)
+	consecErrors      int           // errors since the last successful order (not the breaker's streak)
+	dayOpen           time.Time // anchored day open (UTC or configured TZ)
+
+	peaks             map[string]float64 // per-symbol high-water price since entry (trailing stop)
+	entries           map[string]entry   // per-symbol open quantity and weighted-average entry
 
-	LastErrorTime     time.Time // for cooldowns
-	ErrorCooldown     time.Duration
-	DayOpen           time.Time // anchored day open (UTC or configured TZ)
+	symVol            map[string]*State // per-symbol vol windows when several symbols trade together
+
+	outcomes          []float64 // realized PnL of recent completed trades (Kelly sizing)
+	tripPnL           map[string]float64 // realized PnL so far of each symbol's open round trip
+
+	volEWMALambda     float64   // decay for the EWMA vol updated by PushPriceAt (0 = DefaultEWMALambda)
+	ewmaVar           float64   // EWMA variance of returns
+	ewmaN             int       // returns folded into ewmaVar
 
 	prices            []float64 // rolling window of prices for realized vol
+	priceTimes        []time.Time // arrival time of each entry in prices
 }
 
+// DenialReason is a stable code for why a decision was denied; it is safe to use
+// as a metric label, unlike the free-form Reason text.
+type DenialReason string
+
+const (
+	DenyNoPrice         DenialReason = "no_price"
+	DenyCooldown        DenialReason = "cooldown"
+	DenyDailyLoss       DenialReason = "daily_loss"
+	DenyPositionCap     DenialReason = "position_cap"
+	DenyNoPosition      DenialReason = "no_position"
+	DenyConcentration   DenialReason = "concentration"
+	DenyLotSize         DenialReason = "lot_size"
+	DenyReentryCooldown DenialReason = "reentry_cooldown"
+	DenyBucket          DenialReason = "strategy_budget"
+	DenyOrderCap        DenialReason = "order_cap"
+	DenyDrawdown        DenialReason = "max_drawdown"
+	DenyMinTrade        DenialReason = "min_trade"
+	DenyNoEdge          DenialReason = "no_edge"
+	DenyScaleIn         DenialReason = "scale_in"
+	DenySpread          DenialReason = "spread"
+	DenyLossStreak      DenialReason = "loss_streak"
+)
+
 // Decision is returned when evaluating a trade against limits.
 type Decision struct {
-	Allow       bool    // true if trade allowed
-	Reason      string  // denial reason
-	NotionalUSD float64 // suggested notional size in USD
-	Qty         float64 // suggested asset quantity
+	Allow       bool         // true if trade allowed
+	Code        DenialReason // structured denial reason (empty when allowed)
+	Reason      string       // denial reason
+	NotionalUSD float64      // suggested notional size in USD
+	Qty         float64      // suggested asset quantity
+
+	UnrealizedPnLUSD float64 // open position's PnL at the decision price (0 without an entry)
 }
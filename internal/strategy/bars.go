@@ -0,0 +1,76 @@
+package strategy
+
+import "time"
+
+// Bar is an OHLC candle built from ticks.
+type Bar struct {
+	Start  time.Time
+	End    time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Ticks  int
+	Volume float64 // the price feed carries no traded size, so this is the tick count
+}
+
+// BarAggregator groups ticks into fixed-duration bars aligned to the period
+// (e.g. 1m bars start on the minute), or into fixed-count bars of n ticks.
+type BarAggregator struct {
+	period time.Duration
+	count  int // ticks per bar for count bars (0 = time bars)
+	cur    Bar
+	active bool
+}
+
+func NewBarAggregator(period time.Duration) *BarAggregator {
+	if period <= 0 { period = time.Minute }
+	return &BarAggregator{period: period}
+}
+
+// NewCountBarAggregator closes a bar every n ticks, however long they take;
+// with a quiet feed this keeps bars comparable in activity rather than time.
+func NewCountBarAggregator(n int) *BarAggregator {
+	if n < 1 { n = 1 }
+	return &BarAggregator{count: n}
+}
+
+// Push adds a tick. For time bars, when the tick falls at or after the current
+// bar's end, the finished bar is returned with closed=true and the tick opens
+// the next bar. For count bars, the tick completing the bar closes it at once.
+func (a *BarAggregator) Push(t time.Time, price float64) (bar Bar, closed bool) {
+	if a.count > 0 {
+		if !a.active {
+			a.cur = Bar{Start: t, Open: price, High: price, Low: price}
+			a.active = true
+		}
+		a.add(t, price)
+		if a.cur.Ticks < a.count {
+			return Bar{}, false
+		}
+		a.active = false
+		return a.cur, true
+	}
+	if a.active && !t.Before(a.cur.End) {
+		bar, closed = a.cur, true
+		a.active = false
+	}
+	if !a.active {
+		start := t.Truncate(a.period)
+		a.cur = Bar{Start: start, End: start.Add(a.period), Open: price, High: price, Low: price, Close: price, Ticks: 1, Volume: 1}
+		a.active = true
+		return bar, closed
+	}
+	a.add(t, price)
+	return bar, closed
+}
+
+// add folds a tick into the open bar; count bars end at their latest tick.
+func (a *BarAggregator) add(t time.Time, price float64) {
+	if price > a.cur.High { a.cur.High = price }
+	if price < a.cur.Low { a.cur.Low = price }
+	a.cur.Close = price
+	a.cur.Ticks++
+	a.cur.Volume++
+	if a.count > 0 { a.cur.End = t }
+}
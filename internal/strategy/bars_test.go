@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+)
+
+// The trading loop hands a strategy only closed bars: every tick inside a bar
+// must come back unclosed, so no signal (and no order) can happen intra-bar.
+func TestBarsCloseOnlyAtTheBoundary(t *testing.T) {
+	t0 := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	ticks := []struct {
+		at    time.Duration
+		price float64
+	}{
+		{0, 100}, {20 * time.Second, 103}, {40 * time.Second, 101}, // first bar
+		{60 * time.Second, 102}, {80 * time.Second, 99}, // second bar
+		{130 * time.Second, 104}, // opens the third
+	}
+	a := NewBarAggregator(time.Minute)
+	var closes []Bar
+	var at []time.Duration
+	for _, tk := range ticks {
+		if bar, closed := a.Push(t0.Add(tk.at), tk.price); closed {
+			closes = append(closes, bar)
+			at = append(at, tk.at)
+		}
+	}
+	if len(closes) != 2 {
+		t.Fatalf("%d bars closed, want 2", len(closes))
+	}
+	if at[0] != 60*time.Second || at[1] != 130*time.Second {
+		t.Errorf("bars closed at %v, want on the first ticks past each boundary (1m0s, 2m10s)", at)
+	}
+	want := Bar{Start: t0, End: t0.Add(time.Minute), Open: 100, High: 103, Low: 100, Close: 101, Ticks: 3, Volume: 3}
+	if closes[0] != want {
+		t.Errorf("first bar = %+v, want %+v", closes[0], want)
+	}
+	if closes[1].Open != 102 || closes[1].Close != 99 || closes[1].Ticks != 2 {
+		t.Errorf("second bar = %+v, want 102 -> 99 over 2 ticks", closes[1])
+	}
+}
+
+func TestCountBarsCloseOnTheNthTick(t *testing.T) {
+	a := NewCountBarAggregator(3)
+	t0 := time.Date(2024, 3, 12, 15, 0, 0, 0, time.UTC)
+	var n int
+	for i, px := range []float64{100, 101, 102, 103, 104, 105, 106} {
+		bar, closed := a.Push(t0.Add(time.Duration(i)*time.Second), px)
+		if closed != (i%3 == 2) {
+			t.Fatalf("tick %d: closed=%v", i, closed)
+		}
+		if closed {
+			n++
+			if bar.Ticks != 3 || bar.Close != px {
+				t.Errorf("bar %d = %+v, want 3 ticks closing at %v", n, bar, px)
+			}
+		}
+	}
+	if n != 2 {
+		t.Errorf("%d bars closed over 7 ticks, want 2", n)
+	}
+}
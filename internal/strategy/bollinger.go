@@ -0,0 +1,54 @@
+package strategy
+
+import "github.com/chidi150c/coinlila/internal/util"
+
+// Bollinger is a mean-reversion signal on a moving average with bands k
+// standard deviations either side.
+type Bollinger struct {
+	period int
+	k      float64
+
+	window   []float64
+	prevBand int // -1 below lower, 0 inside, +1 above upper (last price)
+	havePrev bool
+}
+
+func NewBollinger(period int, k float64) *Bollinger {
+	if period < 2 { period = 20 }
+	if k <= 0 { k = 2 }
+	return &Bollinger{period: period, k: k}
+}
+
+// Push adds a closing price. have stays false until period prices have
+// arrived. signal is "buy" when price closes back inside after closing below
+// the lower band, "sell" when it closes back inside from above the upper band,
+// else "".
+func (b *Bollinger) Push(price float64) (have bool, mid, upper, lower float64, signal string) {
+	b.window = append(b.window, price)
+	if len(b.window) > b.period {
+		b.window = b.window[1:]
+	}
+	if len(b.window) < b.period {
+		return false, 0, 0, 0, ""
+	}
+	mid, sd := util.MeanStdDev(b.window)
+	upper, lower = mid+b.k*sd, mid-b.k*sd
+
+	band := 0
+	switch {
+	case price < lower:
+		band = -1
+	case price > upper:
+		band = 1
+	}
+	if b.havePrev && band == 0 {
+		switch b.prevBand {
+		case -1:
+			signal = "buy"
+		case 1:
+			signal = "sell"
+		}
+	}
+	b.prevBand, b.havePrev = band, true
+	return true, mid, upper, lower, signal
+}
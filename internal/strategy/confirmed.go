@@ -0,0 +1,33 @@
+package strategy
+
+import "time"
+
+// NewConfirmed gates base's buys on a higher timeframe: higher is fed one price
+// per barTicks ticks (each bar's close), and a base buy goes through only while
+// higher's latest non-flat signal is also a buy. Sells pass through unchanged so
+// exits are never held back. The result is itself a Strategy, reporting base's
+// lines.
+func NewConfirmed(base, higher Strategy, barTicks int) Strategy {
+	if barTicks < 1 { barTicks = 1 }
+	return &confirmed{base: base, higher: higher, bars: NewCountBarAggregator(barTicks)}
+}
+
+type confirmed struct {
+	base, higher Strategy
+	bars         *BarAggregator // higher-timeframe bars (count bars: ticks carry no time here)
+	trend        string         // higher's latest Buy or Sell, Flat until it has one
+	ready        bool           // higher has warmed up
+}
+
+func (c *confirmed) Push(price float64) Signal {
+	if bar, closed := c.bars.Push(time.Time{}, price); closed {
+		hs := c.higher.Push(bar.Close)
+		c.ready = hs.Ready
+		if hs.Action != Flat && hs.Action != "" { c.trend = hs.Action }
+	}
+	sig := c.base.Push(price)
+	if sig.Action == Buy && (!c.ready || c.trend != Buy) {
+		sig.Action = Flat
+	}
+	return sig
+}
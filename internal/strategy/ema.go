@@ -0,0 +1,63 @@
+package strategy
+
+// emaLine is an exponential moving average over n samples, seeded with the simple
+// average of its first n samples so early values aren't biased toward the first price.
+type emaLine struct {
+	n     int
+	k     float64
+	sum   float64
+	count int
+	value float64
+}
+
+func newEMALine(n int) *emaLine {
+	if n < 1 { n = 1 }
+	return &emaLine{n: n, k: 2 / float64(n+1)}
+}
+
+// push adds a sample and returns the EMA once it is seeded.
+func (e *emaLine) push(x float64) (float64, bool) {
+	if e.count < e.n {
+		e.sum += x
+		e.count++
+		if e.count < e.n {
+			return 0, false
+		}
+		e.value = e.sum / float64(e.n)
+		return e.value, true
+	}
+	e.value += e.k * (x - e.value)
+	return e.value, true
+}
+
+// EMA is a fast/slow exponential moving-average crossover, a drop-in for SMA.
+type EMA struct {
+	fast, slow *emaLine
+	prevDiff   float64
+	havePrev   bool
+}
+
+func NewEMA(fast, slow int) *EMA {
+	return &EMA{fast: newEMALine(fast), slow: newEMALine(slow)}
+}
+
+// Push adds a price. have is false until the slow EMA is seeded; cross is
+// "golden" when fast crosses above slow, "death" when it crosses below, else "".
+func (e *EMA) Push(price float64) (have bool, fast, slow float64, cross string) {
+	f, okF := e.fast.push(price)
+	s, okS := e.slow.push(price)
+	if !okF || !okS {
+		return false, f, s, ""
+	}
+	diff := f - s
+	if e.havePrev {
+		switch {
+		case e.prevDiff <= 0 && diff > 0:
+			cross = "golden"
+		case e.prevDiff >= 0 && diff < 0:
+			cross = "death"
+		}
+	}
+	e.prevDiff, e.havePrev = diff, true
+	return true, f, s, cross
+}
@@ -0,0 +1,34 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+)
+
+// Expected values worked by hand: the fast line (2) has k=2/3 and is seeded at
+// (10+11)/2; the slow line (3) has k=1/2 and is seeded at (10+11+12)/3.
+func TestEMAAgainstHandComputedValues(t *testing.T) {
+	steps := []struct {
+		price      float64
+		have       bool
+		fast, slow float64
+		cross      string
+	}{
+		{10, false, 0, 0, ""},
+		{11, false, 10.5, 0, ""},
+		{12, true, 11.5, 11, ""},
+		{20, true, 103.0 / 6, 15.5, ""},
+		{8, true, 199.0 / 18, 11.75, "death"},
+		{30, true, 1279.0 / 54, 20.875, "golden"},
+	}
+	e := NewEMA(2, 3)
+	for i, s := range steps {
+		have, fast, slow, cross := e.Push(s.price)
+		if have != s.have || cross != s.cross {
+			t.Fatalf("push %d (%v): have=%v cross=%q, want %v %q", i+1, s.price, have, cross, s.have, s.cross)
+		}
+		if math.Abs(fast-s.fast) > 1e-9 || math.Abs(slow-s.slow) > 1e-9 {
+			t.Errorf("push %d (%v): fast %v slow %v, want %v and %v", i+1, s.price, fast, slow, s.fast, s.slow)
+		}
+	}
+}
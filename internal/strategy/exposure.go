@@ -0,0 +1,14 @@
+package strategy
+
+// TargetExposure turns a fast/slow moving-average pair into a desired exposure in
+// [-1, 1]: the MA spread as a percent of slow, scaled so that a spread of
+// fullAtPct or more means full exposure (a weak crossover gives a partial one).
+func TargetExposure(fast, slow, fullAtPct float64) float64 {
+	if slow <= 0 || fullAtPct <= 0 {
+		return 0
+	}
+	x := (fast - slow) / slow * 100 / fullAtPct
+	if x > 1 { return 1 }
+	if x < -1 { return -1 }
+	return x
+}
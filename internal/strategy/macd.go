@@ -0,0 +1,40 @@
+package strategy
+
+// MACD is the moving-average convergence/divergence: the fast EMA minus the slow
+// EMA, with an EMA of that difference as the signal line.
+type MACD struct {
+	fast, slow, signal *emaLine
+	prevHist           float64
+	havePrev           bool
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: newEMALine(fast), slow: newEMALine(slow), signal: newEMALine(signal)}
+}
+
+// Push adds a price. have is false until the slow EMA is seeded and the signal
+// EMA has seen enough MACD values to be seeded too. cross is "golden" when the
+// MACD line crosses above the signal line, "death" when it crosses below.
+func (m *MACD) Push(price float64) (have bool, macd, signalLine, histogram float64, cross string) {
+	f, okF := m.fast.push(price)
+	s, okS := m.slow.push(price)
+	if !okF || !okS {
+		return false, 0, 0, 0, ""
+	}
+	macd = f - s
+	signalLine, ok := m.signal.push(macd)
+	if !ok {
+		return false, macd, 0, 0, ""
+	}
+	histogram = macd - signalLine
+	if m.havePrev {
+		switch {
+		case m.prevHist <= 0 && histogram > 0:
+			cross = "golden"
+		case m.prevHist >= 0 && histogram < 0:
+			cross = "death"
+		}
+	}
+	m.prevHist, m.havePrev = histogram, true
+	return true, macd, signalLine, histogram, cross
+}
@@ -0,0 +1,74 @@
+package strategy
+
+// RSI is a Wilder relative-strength-index mean-reversion signal.
+type RSI struct {
+	period               int
+	oversold, overbought float64
+
+	last     float64
+	n        int // prices seen
+	sumGain  float64
+	sumLoss  float64
+	avgGain  float64
+	avgLoss  float64
+	prev     float64
+	havePrev bool
+}
+
+func NewRSI(period int, oversold, overbought float64) *RSI {
+	if period < 1 { period = 14 }
+	return &RSI{period: period, oversold: oversold, overbought: overbought}
+}
+
+// Push adds a price. have stays false until period+1 prices have arrived (the
+// first average needs period changes). signal is "buy" when RSI crosses up
+// through oversold, "sell" when it crosses down through overbought, else "".
+func (r *RSI) Push(price float64) (have bool, rsi float64, signal string) {
+	r.n++
+	if r.n == 1 {
+		r.last = price
+		return false, 0, ""
+	}
+	gain, loss := 0.0, 0.0
+	if d := price - r.last; d > 0 {
+		gain = d
+	} else {
+		loss = -d
+	}
+	r.last = price
+
+	p := float64(r.period)
+	switch {
+	case r.n <= r.period:
+		r.sumGain += gain
+		r.sumLoss += loss
+		return false, 0, ""
+	case r.n == r.period+1:
+		// seed with the simple average of the first period changes
+		r.avgGain = (r.sumGain + gain) / p
+		r.avgLoss = (r.sumLoss + loss) / p
+	default:
+		// Wilder smoothing
+		r.avgGain = (r.avgGain*(p-1) + gain) / p
+		r.avgLoss = (r.avgLoss*(p-1) + loss) / p
+	}
+
+	switch {
+	case r.avgLoss == 0 && r.avgGain == 0:
+		rsi = 50
+	case r.avgLoss == 0:
+		rsi = 100
+	default:
+		rsi = 100 - 100/(1+r.avgGain/r.avgLoss)
+	}
+	if r.havePrev {
+		switch {
+		case r.prev < r.oversold && rsi >= r.oversold:
+			signal = "buy"
+		case r.prev > r.overbought && rsi <= r.overbought:
+			signal = "sell"
+		}
+	}
+	r.prev, r.havePrev = rsi, true
+	return true, rsi, signal
+}
@@ -0,0 +1,162 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signal actions.
+const (
+	Buy  = "buy"
+	Sell = "sell"
+	Flat = "flat"
+)
+
+// Signal is a strategy's verdict on one price.
+type Signal struct {
+	Action string  // Buy, Sell or Flat
+	Ready  bool    // false while the indicators are still warming up
+	Fast   float64 // faster line: fast MA, MACD line, RSI, upper band
+	Slow   float64 // slower/reference line: slow MA, signal line, RSI, lower band
+}
+
+// Strategy is all the trading loop needs from a strategy.
+type Strategy interface {
+	Push(price float64) Signal
+}
+
+// Params looks up a strategy parameter by its env-style key, e.g. "RSI_PERIOD".
+type Params func(key, def string) string
+
+// Factory builds one independent Strategy instance (one per symbol).
+type Factory func(p Params) Strategy
+
+var registry = map[string]Factory{}
+
+// Register makes a strategy selectable by name (STRATEGY=name).
+func Register(name string, f Factory) { registry[name] = f }
+
+// New builds the strategy registered under name.
+func New(name string, p Params) (Strategy, error) {
+	f, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (have %s)", name, strings.Join(Names(), ", "))
+	}
+	return f(p), nil
+}
+
+// Names lists the registered strategies, sorted.
+func Names() []string {
+	out := make([]string, 0, len(registry))
+	for n := range registry { out = append(out, n) }
+	sort.Strings(out)
+	return out
+}
+
+// ParseAssignments parses "SYMBOL:name,..." (e.g. "ETH-USD:rsi,SOL-USD:macd"),
+// the strategies some symbols run instead of the default one.
+func ParseAssignments(spec string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		sym, name, ok := strings.Cut(part, ":")
+		sym, name = strings.TrimSpace(sym), strings.TrimSpace(name)
+		if !ok || sym == "" {
+			return nil, fmt.Errorf("symbol strategies: %q is not symbol:strategy", part)
+		}
+		if _, known := registry[name]; !known {
+			return nil, fmt.Errorf("symbol strategies: unknown strategy %q (have %s)", name, strings.Join(Names(), ", "))
+		}
+		out[sym] = name
+	}
+	return out, nil
+}
+
+// Crossover adapts a fast/slow line pair (SMA, EMA) to Strategy: a "golden"
+// cross buys, a "death" cross sells.
+func Crossover(c interface {
+	Push(price float64) (have bool, fast, slow float64, cross string)
+}) Strategy {
+	return crossover{c}
+}
+
+type crossover struct {
+	c interface {
+		Push(price float64) (have bool, fast, slow float64, cross string)
+	}
+}
+
+func (x crossover) Push(price float64) Signal {
+	have, fast, slow, cross := x.c.Push(price)
+	return Signal{Action: crossAction(cross), Ready: have, Fast: fast, Slow: slow}
+}
+
+type rsiStrategy struct{ *RSI }
+
+func (r rsiStrategy) Push(price float64) Signal {
+	have, v, sig := r.RSI.Push(price)
+	return Signal{Action: action(sig), Ready: have, Fast: v, Slow: v}
+}
+
+type macdStrategy struct{ *MACD }
+
+func (m macdStrategy) Push(price float64) Signal {
+	have, macd, line, _, cross := m.MACD.Push(price)
+	return Signal{Action: crossAction(cross), Ready: have, Fast: macd, Slow: line}
+}
+
+type bollingerStrategy struct{ *Bollinger }
+
+func (b bollingerStrategy) Push(price float64) Signal {
+	have, _, upper, lower, sig := b.Bollinger.Push(price)
+	return Signal{Action: action(sig), Ready: have, Fast: upper, Slow: lower}
+}
+
+// crossAction maps a "golden"/"death" line cross to Buy/Sell.
+func crossAction(cross string) string {
+	switch cross {
+	case "golden":
+		return Buy
+	case "death":
+		return Sell
+	}
+	return Flat
+}
+
+// action passes a "buy"/"sell" signal through; anything else is Flat.
+func action(sig string) string {
+	if sig == Buy || sig == Sell { return sig }
+	return Flat
+}
+
+func atoi(p Params, key, def string) int {
+	v, _ := strconv.Atoi(p(key, def))
+	return v
+}
+
+func atof(p Params, key, def string) float64 {
+	v, _ := strconv.ParseFloat(p(key, def), 64)
+	return v
+}
+
+func init() {
+	Register("sma", func(p Params) Strategy {
+		return Crossover(NewSMA(atoi(p, "SMA_FAST", "10"), atoi(p, "SMA_SLOW", "30")))
+	})
+	Register("ema", func(p Params) Strategy {
+		return Crossover(NewEMA(atoi(p, "SMA_FAST", "10"), atoi(p, "SMA_SLOW", "30")))
+	})
+	Register("rsi", func(p Params) Strategy {
+		return rsiStrategy{NewRSI(atoi(p, "RSI_PERIOD", "14"), atof(p, "RSI_OVERSOLD", "30"), atof(p, "RSI_OVERBOUGHT", "70"))}
+	})
+	Register("macd", func(p Params) Strategy {
+		return macdStrategy{NewMACD(atoi(p, "MACD_FAST", "12"), atoi(p, "MACD_SLOW", "26"), atoi(p, "MACD_SIGNAL", "9"))}
+	})
+	Register("bollinger", func(p Params) Strategy {
+		return bollingerStrategy{NewBollinger(atoi(p, "BOLLINGER_PERIOD", "20"), atof(p, "BOLLINGER_K", "2"))}
+	})
+}
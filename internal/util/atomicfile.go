@@ -1,14 +1,18 @@
 package util
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
-// writeFileAtomic writes data to path atomically (tmp file + fsync + rename).
+// WriteFileAtomic writes data to path atomically (tmp file + fsync + rename).
 // On Unix it also fsyncs the parent directory to harden the rename durability.
-func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
 	dir := filepath.Dir(path)
 	tmp, err := os.CreateTemp(dir, ".tmp-*")
 	if err != nil { return err }
@@ -33,11 +37,34 @@ func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
 func acquirePidLock(lockPath string) (*os.File, error) {
 	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
 	if err != nil { return nil, err }
-	_, _ = f.Write([]byte{})
+	_, _ = fmt.Fprintf(f, "%d\n", os.Getpid())
 
 	// mark close-on-exec
-	if st := syscall.CloseOnExec(int(f.Fd())); st != nil { /* ignore */ }
+	syscall.CloseOnExec(int(f.Fd()))
 	return f, nil
 }
 
 func releasePidLock(f *os.File) { if f != nil { path := f.Name(); f.Close(); _ = os.Remove(path) } }
+
+// AcquireLock takes an exclusive flock on the file at path so a second process
+// using the same state files fails fast instead of clobbering them. The kernel
+// drops the lock when the holder exits, however it exits, so a crash (or a
+// restart that reuses the pid, as PID 1 in a container does) never leaves a
+// stale lock behind. The file records the holder's pid for the error message;
+// release unlocks it and leaves the file in place.
+func AcquireLock(path string) (release func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil { return nil, err }
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if !errors.Is(err, syscall.EWOULDBLOCK) { return nil, fmt.Errorf("lock %s: %w", path, err) }
+		b, _ := os.ReadFile(path)
+		holder := strings.TrimSpace(string(b))
+		if holder == "" { holder = "unknown" }
+		return nil, fmt.Errorf("%s is held by another instance (pid %s)", path, holder)
+	}
+	syscall.CloseOnExec(int(f.Fd()))
+	_ = f.Truncate(0)
+	_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return func() { f.Close() }, nil
+}
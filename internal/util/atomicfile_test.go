@@ -0,0 +1,41 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcquireLockSecondFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.lock")
+	release, err := AcquireLock(path)
+	if err != nil {
+		t.Fatalf("first AcquireLock: %v", err)
+	}
+	if _, err := AcquireLock(path); err == nil {
+		t.Fatalf("second AcquireLock succeeded while the lock is held")
+	} else if !strings.Contains(err.Error(), strconv.Itoa(os.Getpid())) {
+		t.Errorf("error %q does not name the holder's pid", err)
+	}
+	release()
+	release2, err := AcquireLock(path)
+	if err != nil {
+		t.Fatalf("AcquireLock after release: %v", err)
+	}
+	release2()
+}
+
+func TestAcquireLockIgnoresLeftoverFile(t *testing.T) {
+	// a crashed holder leaves its file (and pid, maybe ours after a restart) behind
+	path := filepath.Join(t.TempDir(), "state.lock")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	release, err := AcquireLock(path)
+	if err != nil {
+		t.Fatalf("AcquireLock over a leftover file: %v", err)
+	}
+	release()
+}
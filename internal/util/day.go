@@ -2,21 +2,50 @@ package util
 
 import "time"
 
-// TodayOpen returns the local midnight (00:00) for `now` in tz.
-func TodayOpen(tz string, now time.Time) time.Time {
+// Every helper below takes the trading-day anchor: how long after local
+// midnight in tz the day opens (0 = midnight), e.g. 17h for a New York session.
+
+// TodayOpen returns the start of the trading day containing `now` in tz: the
+// day's anchor, or the previous day's when now is before it.
+func TodayOpen(tz string, anchor time.Duration, now time.Time) time.Time {
 	loc, err := time.LoadLocation(tz)
 	if err != nil { loc = time.UTC }
-	y, m, d := now.In(loc).Date()
-	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	local := now.In(loc)
+	y, m, d := local.Date()
+	open := atAnchor(y, m, d, anchor, loc)
+	if local.Before(open) {
+		open = atAnchor(y, m, d-1, anchor, loc)
+	}
+	return open
+}
+
+// NextOpen returns the next trading-day open after `now` in tz. It steps by
+// calendar day, so a DST change makes that day 23 or 25 hours long.
+func NextOpen(tz string, anchor time.Duration, now time.Time) time.Time {
+	o := TodayOpen(tz, anchor, now)
+	y, m, d := o.Date()
+	return atAnchor(y, m, d+1, anchor, o.Location())
+}
+
+// atAnchor is the wall-clock time anchor after midnight on the given day.
+func atAnchor(y int, m time.Month, d int, anchor time.Duration, loc *time.Location) time.Time {
+	return time.Date(y, m, d, int(anchor/time.Hour), int(anchor%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// WeekOpen returns the trading-day open on the Monday of now's ISO week in tz.
+func WeekOpen(tz string, anchor time.Duration, now time.Time) time.Time {
+	o := TodayOpen(tz, anchor, now)
+	back := (int(o.Weekday()) + 6) % 7 // days since Monday
+	return o.AddDate(0, 0, -back)
 }
 
-// NextOpen returns the next local midnight after `now` in tz.
-func NextOpen(tz string, now time.Time) time.Time {
-	o := TodayOpen(tz, now)
-	return o.Add(24 * time.Hour)
+// MonthOpen returns the trading-day open on the first day of now's month in tz.
+func MonthOpen(tz string, anchor time.Duration, now time.Time) time.Time {
+	o := TodayOpen(tz, anchor, now)
+	return o.AddDate(0, 0, 1-o.Day())
 }
 
-// SameTradingDay checks if a and b are on the same local day in tz.
-func SameTradingDay(tz string, a, b time.Time) bool {
-	return TodayOpen(tz, a).Equal(TodayOpen(tz, b))
+// SameTradingDay checks if a and b fall in the same trading day in tz.
+func SameTradingDay(tz string, anchor time.Duration, a, b time.Time) bool {
+	return TodayOpen(tz, anchor, a).Equal(TodayOpen(tz, anchor, b))
 }
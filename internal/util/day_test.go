@@ -0,0 +1,62 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoc(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil { t.Skipf("no zoneinfo for %s: %v", name, err) }
+	return loc
+}
+
+// A 17:00 New York session anchor across both 2024 DST changes: the anchor stays
+// at 17:00 local, so those trading days are 23 and 25 hours long.
+func TestDayOpenAnchorAcrossDST(t *testing.T) {
+	const tz = "America/New_York"
+	ny := mustLoc(t, tz)
+	anchor := 17 * time.Hour
+	cases := []struct {
+		name       string
+		now        time.Time
+		open, next time.Time
+		hours      float64
+	}{
+		{"spring forward, before the anchor", time.Date(2024, 3, 10, 16, 59, 0, 0, ny),
+			time.Date(2024, 3, 9, 17, 0, 0, 0, ny), time.Date(2024, 3, 10, 17, 0, 0, 0, ny), 23},
+		{"spring forward, at the anchor", time.Date(2024, 3, 10, 17, 0, 0, 0, ny),
+			time.Date(2024, 3, 10, 17, 0, 0, 0, ny), time.Date(2024, 3, 11, 17, 0, 0, 0, ny), 24},
+		{"fall back, before the anchor", time.Date(2024, 11, 3, 1, 30, 0, 0, ny),
+			time.Date(2024, 11, 2, 17, 0, 0, 0, ny), time.Date(2024, 11, 3, 17, 0, 0, 0, ny), 25},
+	}
+	for _, c := range cases {
+		open, next := TodayOpen(tz, anchor, c.now), NextOpen(tz, anchor, c.now)
+		if !open.Equal(c.open) || !next.Equal(c.next) {
+			t.Errorf("%s: open %v next %v, want %v and %v", c.name, open, next, c.open, c.next)
+		}
+		if h := next.Sub(open).Hours(); h != c.hours {
+			t.Errorf("%s: trading day is %vh, want %vh", c.name, h, c.hours)
+		}
+	}
+	// the spring-forward night belongs to the day that opened the evening before
+	if !SameTradingDay(tz, anchor, time.Date(2024, 3, 9, 23, 0, 0, 0, ny), time.Date(2024, 3, 10, 10, 0, 0, 0, ny)) {
+		t.Errorf("23:00 and 10:00 across the DST change split into two trading days")
+	}
+	if SameTradingDay(tz, anchor, time.Date(2024, 3, 10, 16, 59, 0, 0, ny), time.Date(2024, 3, 10, 17, 0, 0, 0, ny)) {
+		t.Errorf("16:59 and 17:00 are in the same trading day")
+	}
+}
+
+func TestDayOpenMidnightDefault(t *testing.T) {
+	const tz = "America/New_York"
+	ny := mustLoc(t, tz)
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, ny)
+	if got, want := TodayOpen(tz, 0, now), time.Date(2024, 3, 10, 0, 0, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("TodayOpen = %v, want %v", got, want)
+	}
+	if h := NextOpen(tz, 0, now).Sub(TodayOpen(tz, 0, now)).Hours(); h != 23 {
+		t.Errorf("2024-03-10 is %vh long, want 23h", h)
+	}
+}
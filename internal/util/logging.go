@@ -0,0 +1,22 @@
+package util
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// NewLogger builds the process logger: format "json" gives one JSON object per
+// line for log aggregators, anything else readable text. level is debug, info,
+// warn or error (default info).
+func NewLogger(format, level string, w io.Writer) *slog.Logger {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		lv = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
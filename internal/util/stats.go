@@ -0,0 +1,21 @@
+package util
+
+import "math"
+
+// MeanStdDev returns the mean and population standard deviation of xs
+// (0, 0 when xs is empty).
+func MeanStdDev(xs []float64) (mean, sd float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	var varsum float64
+	for _, x := range xs {
+		d := x - mean
+		varsum += d * d
+	}
+	return mean, math.Sqrt(varsum / float64(len(xs)))
+}
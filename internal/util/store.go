@@ -3,11 +3,21 @@ package util
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
+// SnapshotSchemaVersion is the DaySnapshot layout written by this build.
+// History: 0 = unversioned original; 1 = adds schema_version (weekly/monthly
+// anchors and halted may be absent and are seeded by the day manager).
+const SnapshotSchemaVersion = 1
+
 type DaySnapshot struct {
+	SchemaVersion    int     `json:"schema_version"`
+
 	// Trading day anchor
 	DayOpenISO       string  `json:"day_open_iso"`
 	Timezone         string  `json:"timezone"`
@@ -17,29 +27,77 @@ type DaySnapshot struct {
 
 	// Optional helpful counters (persisted across restarts)
 	OrdersToday      int     `json:"orders_today"`
+	OrdersBySymbol   map[string]int `json:"orders_by_symbol,omitempty"`
+	BucketOrders     map[string]int     `json:"bucket_orders,omitempty"`       // per-strategy budget usage (risk buckets)
+	BucketNotional   map[string]float64 `json:"bucket_notional_usd,omitempty"`
 	RealizedPnLUSD   float64 `json:"realized_pnl_usd"`
+
+	// Tighter loss cap applied after the baseline had to be reconstructed
+	InterimMaxLossPct float64 `json:"interim_max_loss_pct,omitempty"`
+
+	// Daily loss limit already hit today: stay halted across restarts
+	Halted            bool    `json:"halted,omitempty"`
+	HaltCause         string  `json:"halt_cause,omitempty"` // "loss_streak" or "daily_loss" (absent = daily loss)
+	LossStreak        int     `json:"loss_streak,omitempty"` // losing closed trades in a row so far today
+
+	// Weekly/monthly drawdown anchors; absent in older files, in which case
+	// they are seeded from current equity on load
+	WeekOpenISO       string  `json:"week_open_iso,omitempty"`
+	EquityAtWeekOpen  float64 `json:"equity_at_week_open_usd,omitempty"`
+	MonthOpenISO      string  `json:"month_open_iso,omitempty"`
+	EquityAtMonthOpen float64 `json:"equity_at_month_open_usd,omitempty"`
+
+	// Trailing-stop high-water price and average entry per symbol, so exits
+	// continue from where they were after a restart; absent = none recorded
+	Peaks             map[string]float64 `json:"peaks,omitempty"`
+	Entries           map[string]float64 `json:"entries,omitempty"`
 }
 
+// LoadSnapshot reads a snapshot. One written by an older schema is migrated and
+// rewritten atomically in the current one (a .bak fallback is written back to
+// its primary path); a newer schema is an error rather than being misread. A
+// failed rewrite is only logged: the migrated snapshot is still good, and
+// dropping it would reset the day's loss baseline.
 func LoadSnapshot(path string) (DaySnapshot, error) {
 	b, err := os.ReadFile(path)
 	if err != nil { return DaySnapshot{}, err }
 	var s DaySnapshot
 	if err := json.Unmarshal(b, &s); err != nil { return DaySnapshot{}, err }
+	switch {
+	case s.SchemaVersion > SnapshotSchemaVersion:
+		return DaySnapshot{}, fmt.Errorf("%s: schema version %d is newer than supported %d", path, s.SchemaVersion, SnapshotSchemaVersion)
+	case s.SchemaVersion < SnapshotSchemaVersion:
+		s = migrateSnapshot(s)
+		if err := SaveSnapshot(strings.TrimSuffix(path, ".bak"), s); err != nil {
+			slog.Warn("migrated day snapshot not rewritten", "path", path, "err", err)
+		}
+	}
 	return s, nil
 }
 
+// migrateSnapshot upgrades s one schema version at a time to the current one.
+func migrateSnapshot(s DaySnapshot) DaySnapshot {
+	if s.SchemaVersion < 1 {
+		// v0 → v1: every v0 field keeps its meaning; fields added later stay zero,
+		// which the day manager treats as "not recorded" and seeds
+		s.SchemaVersion = 1
+	}
+	return s
+}
+
 func SaveSnapshot(path string, s DaySnapshot) error {
+	s.SchemaVersion = SnapshotSchemaVersion
 	b, err := json.MarshalIndent(s, "", "  ")
 	if err != nil { return err }
 	// best-effort .bak
 	_ = os.WriteFile(path+".bak", b, 0o600)
-	return writeFileAtomic(path, b, 0o600)
+	return WriteFileAtomic(path, b, 0o600)
 }
 
 // SeedForToday builds a snapshot for the current trading day.
-func SeedForToday(tz string, now time.Time, equityAtOpen float64) DaySnapshot {
+func SeedForToday(tz string, anchor time.Duration, now time.Time, equityAtOpen float64) DaySnapshot {
 	return DaySnapshot{
-		DayOpenISO:      TodayOpen(tz, now).UTC().Format(time.RFC3339),
+		DayOpenISO:      TodayOpen(tz, anchor, now).UTC().Format(time.RFC3339),
 		Timezone:        tz,
 		EquityAtOpenUSD: equityAtOpen,
 		OrdersToday:     0,
@@ -51,5 +109,5 @@ func ParseDayOpenISO(s string) (time.Time, error) {
 	if s == "" { return time.Time{}, errors.New("empty day_open_iso") }
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil { return time.Time{}, err }
-	return t
+	return t, nil
 }
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSnapshotMigratesV0(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "day_snapshot.json")
+	v0 := `{"day_open_iso":"2024-03-10T00:00:00Z","timezone":"UTC","equity_at_open_usd":12345.67,"orders_today":4,"realized_pnl_usd":-12.5}`
+	if err := os.WriteFile(path, []byte(v0), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if s.SchemaVersion != SnapshotSchemaVersion || s.EquityAtOpenUSD != 12345.67 || s.OrdersToday != 4 || s.RealizedPnLUSD != -12.5 || s.DayOpenISO != "2024-03-10T00:00:00Z" {
+		t.Errorf("migrated snapshot = %+v", s)
+	}
+	// the file on disk is rewritten in the current schema
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"schema_version": 1`) || !strings.Contains(string(b), `"equity_at_open_usd": 12345.67`) {
+		t.Errorf("rewritten file:\n%s", b)
+	}
+}
+
+func TestLoadSnapshotRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "day_snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"schema_version":99,"equity_at_open_usd":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(path); err == nil {
+		t.Errorf("a schema 99 snapshot loaded")
+	}
+}
+
+// Migrating the .bak fallback must not fail the load when the primary can't be
+// written, nor leave a .bak.bak behind.
+func TestLoadSnapshotMigratesBakWhenRewriteFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "day_snapshot.json")
+	// a non-empty directory in place of the primary makes the rewrite fail
+	if err := os.MkdirAll(filepath.Join(path, "x"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	v0 := `{"day_open_iso":"2024-03-10T00:00:00Z","timezone":"UTC","equity_at_open_usd":12345.67}`
+	if err := os.WriteFile(path+".bak", []byte(v0), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSnapshot(path + ".bak")
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if s.EquityAtOpenUSD != 12345.67 || s.SchemaVersion != SnapshotSchemaVersion {
+		t.Errorf("migrated snapshot = %+v", s)
+	}
+	if _, err := os.Stat(path + ".bak.bak"); err == nil {
+		t.Errorf("migrating the .bak wrote a .bak.bak")
+	}
+}
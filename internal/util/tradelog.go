@@ -0,0 +1,157 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trade is one executed order.
+type Trade struct {
+	Time           time.Time `json:"time"`
+	Symbol         string    `json:"symbol"`
+	Side           string    `json:"side"`
+	Qty            float64   `json:"qty"`
+	Price          float64   `json:"price"`
+	NotionalUSD    float64   `json:"notional_usd"`
+	RealizedPnLUSD float64   `json:"realized_pnl_usd"`
+}
+
+// TradeLog appends executed orders to a JSONL file, one line per trade. The file
+// holds a single trading day: at the first trade of a new day it is renamed to
+// <name>-YYYY-MM-DD<ext> and a fresh file is started. A nil *TradeLog discards.
+type TradeLog struct {
+	mu   sync.Mutex
+	Path string
+	TZ   string
+	Open time.Duration // trading-day anchor after midnight in TZ (see TodayOpen)
+	f    *os.File
+	day  time.Time // trading-day open of the trades in the current file
+}
+
+// OpenTradeLog opens path for appending, first rotating it away if it holds an
+// earlier trading day's trades.
+func OpenTradeLog(path, tz string, anchor time.Duration, now time.Time) (*TradeLog, error) {
+	l := &TradeLog{Path: path, TZ: tz, Open: anchor, day: TodayOpen(tz, anchor, now)}
+	trades, err := readTrades(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(trades) > 0 && !SameTradingDay(tz, anchor, trades[0].Time, now) {
+		if err := l.rotate(trades[0].Time); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends t (Time defaults to now) as one JSON line and syncs it to disk.
+func (l *TradeLog) Record(t Trade) error {
+	if l == nil { return nil }
+	if t.Time.IsZero() { t.Time = time.Now() }
+	if t.NotionalUSD == 0 { t.NotionalUSD = t.Qty * t.Price }
+	b, err := json.Marshal(t)
+	if err != nil { return err }
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !SameTradingDay(l.TZ, l.Open, l.day, t.Time) {
+		_ = l.f.Close()
+		if err := l.rotate(l.day); err != nil { return err }
+		if err := l.open(); err != nil { return err }
+		l.day = TodayOpen(l.TZ, l.Open, t.Time)
+	}
+	// a single write on an O_APPEND file: a crash leaves whole lines only
+	if _, err := l.f.Write(append(b, '\n')); err != nil { return err }
+	return l.f.Sync()
+}
+
+// Today returns the trades recorded so far in the current trading day.
+func (l *TradeLog) Today() ([]Trade, error) {
+	if l == nil { return nil, nil }
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return readTrades(l.Path)
+}
+
+// ExportCSV writes the current day's trades to path as CSV (atomically).
+func (l *TradeLog) ExportCSV(path string) error {
+	trades, err := l.Today()
+	if err != nil { return err }
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"time", "symbol", "side", "qty", "price", "notional_usd", "realized_pnl_usd"})
+	for _, t := range trades {
+		_ = w.Write([]string{
+			t.Time.UTC().Format(time.RFC3339Nano), t.Symbol, t.Side,
+			strconv.FormatFloat(t.Qty, 'f', -1, 64),
+			strconv.FormatFloat(t.Price, 'f', -1, 64),
+			strconv.FormatFloat(t.NotionalUSD, 'f', -1, 64),
+			strconv.FormatFloat(t.RealizedPnLUSD, 'f', -1, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil { return err }
+	return WriteFileAtomic(path, buf.Bytes(), 0o600)
+}
+
+func (l *TradeLog) Close() error {
+	if l == nil { return nil }
+	return l.f.Close()
+}
+
+func (l *TradeLog) open() error {
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil { return err }
+	l.f = f
+	return nil
+}
+
+// rotate renames the current file to its dated archive name.
+func (l *TradeLog) rotate(day time.Time) error {
+	ext := filepath.Ext(l.Path)
+	dst := strings.TrimSuffix(l.Path, ext) + "-" + TodayOpen(l.TZ, l.Open, day).Format("2006-01-02") + ext
+	if err := os.Rename(l.Path, dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// LoadTodayTrades reconstructs today's fills from a trade log file on restart.
+func LoadTodayTrades(path, tz string, anchor time.Duration, now time.Time) ([]Trade, error) {
+	all, err := readTrades(path)
+	if err != nil { return nil, err }
+	var out []Trade
+	for _, t := range all {
+		if SameTradingDay(tz, anchor, t.Time, now) { out = append(out, t) }
+	}
+	return out, nil
+}
+
+// readTrades parses a trade log; a torn final line (crash mid-write) is skipped.
+func readTrades(path string) ([]Trade, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) { return nil, nil }
+	if err != nil { return nil, err }
+	defer f.Close()
+	var out []Trade
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var t Trade
+		if json.Unmarshal(sc.Bytes(), &t) == nil {
+			out = append(out, t)
+		}
+	}
+	return out, sc.Err()
+}
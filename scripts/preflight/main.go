@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/joho/godotenv"
+
+	"github.com/chidi150c/coinlila/internal/config"
+	"github.com/chidi150c/coinlila/internal/exchange"
 )
 
 func fail(msg string) { log.Fatalf("FAIL: %s", msg) }
@@ -17,27 +20,36 @@ func main() {
 	if _, err := os.Stat(".env"); err == nil {
 		if err := godotenv.Load(".env"); err != nil { fail("cannot load .env") }
 	} else { fail(".env missing") }
+	config.ApplySandbox()
 
-	mode := os.Getenv("MODE")
-	if mode == "" { fail("MODE missing") }
-	if mode != "paper" { fail("MODE must be 'paper' at Phase 0") }
-	pass("MODE is paper")
-
-	symbol := os.Getenv("SYMBOL")
-	if symbol == "" || !strings.Contains(symbol, "-") { fail("SYMBOL missing or not like BASE-QUOTE (e.g., BTC-USD)") }
-	pass("SYMBOL looks OK: " + symbol)
+	// The bot's own startup checks (mode, symbols, endpoints, numeric knobs)
+	if err := config.Validate(); err != nil {
+		for _, e := range strings.Split(err.Error(), "\n") { fmt.Println("FAIL:", e) }
+		os.Exit(1)
+	}
+	pass("config valid")
 
-	// Coinbase endpoints present (not verifying correctness yet)
-	if os.Getenv("COINBASE_API_BASE") == "" || os.Getenv("COINBASE_WS_URL") == "" {
-		fail("COINBASE_API_BASE or COINBASE_WS_URL missing")
+	mode := os.Getenv("MODE")
+	switch {
+	case config.Sandbox():
+		fmt.Println("WARN: SANDBOX ON: MODE=live sends real orders to the Coinbase sandbox (" + os.Getenv("COINBASE_API_BASE") + "), fake money")
+	case os.Getenv("COINBASE_SANDBOX") == "true":
+		fmt.Println("NOTE: COINBASE_SANDBOX=true has no effect with MODE=" + mode + " (only live orders use it)")
+	case mode == "live":
+		fail("MODE=live without COINBASE_SANDBOX=true would trade real money; not allowed at Phase 0")
+	default:
+		fmt.Println("NOTE: sandbox off (MODE=" + mode + " simulates orders locally)")
 	}
-	pass("Exchange endpoints present")
+	pass("MODE is " + mode)
+	symbols := config.ParseSymbols(os.Getenv("SYMBOL"))
 
-	// Risk knobs present
-	for _, k := range []string{"MAX_POSITION_USD","MAX_LOSS_PCT_DAY","MIN_TRADE_USD"} {
-		if os.Getenv(k) == "" { fail(k + " missing") }
+	// Each symbol must exist and accept market orders on the venue
+	for _, symbol := range symbols {
+		meta, err := exchange.FetchCoinbaseProduct(nil, os.Getenv("COINBASE_API_BASE"), symbol)
+		if err != nil { fail("product lookup: " + err.Error()) }
+		if err := meta.Tradable(); err != nil { fail(err.Error()) }
+		pass("Product tradable: " + symbol)
 	}
-	pass("Risk knobs present")
 
 	// Warn if any live-ish hints
 	key := os.Getenv("COINBASE_API_KEY")